@@ -7,6 +7,7 @@ require (
 	github.com/Data-Corruption/stdx v0.4.0
 	github.com/urfave/cli/v3 v3.4.1
 	golang.org/x/mod v0.27.0
+	golang.org/x/term v0.34.0
 )
 
 require golang.org/x/sys v0.35.0 // indirect
@@ -0,0 +1,771 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goweb/go/database/config"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+var Config = &cli.Command{
+	Name:  "config",
+	Usage: "view and change configuration",
+	Commands: []*cli.Command{
+		{
+			Name:        "get",
+			Usage:       "print the value of a key",
+			ArgsUsage:   "KEY",
+			Description: "KEY may be a path into a struct, list or map value, e.g. `config get cors.origins[0]` for a key cors holding {\"origins\": [...]}.",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "source",
+					Usage: "also print the layer the value came from (default|db|profile|env)",
+				},
+				&cli.BoolFlag{
+					Name:  "reveal",
+					Usage: "print sensitive values instead of " + config.Redacted,
+				},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				if cmd.NArg() != 1 {
+					return fmt.Errorf("expected exactly one argument: KEY")
+				}
+				key := cmd.Args().First()
+				warnDeprecated(cfg, key)
+				val, src, err := cfg.Lookup(key)
+				if err != nil {
+					return err
+				}
+				if !cmd.Bool("reveal") {
+					val = cfg.Redact(key, val)
+				}
+				if cmd.Bool("source") {
+					fmt.Printf("%s (%s)\n", config.Format(val), src)
+					return nil
+				}
+				fmt.Println(config.Format(val))
+				return nil
+			},
+		},
+		{
+			Name:      "set",
+			Usage:     "set the value of one or more keys",
+			ArgsUsage: "KEY VALUE [KEY VALUE...]",
+			Description: "VALUE is parsed using the key's type. Strings are taken as is, ints/bools/structs are parsed as JSON.\nMultiple pairs are applied together in one transaction, e.g. `config set tlsKeyPath k.pem tlsCertPath c.pem useTLS true`.\n" +
+				"KEY may be a path into a struct, list or map value, e.g. `config set cors.origins[1] https://x` for a key cors holding {\"origins\": [...]},\n" +
+				"the index past the end appends.",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				args := cmd.Args().Slice()
+				if len(args) == 0 || len(args)%2 != 0 {
+					return fmt.Errorf("expected KEY VALUE pairs")
+				}
+				raw := make(map[string]string, len(args)/2)
+				keys := make([]string, 0, len(args)/2)
+				for i := 0; i < len(args); i += 2 {
+					if _, dup := raw[args[i]]; dup {
+						return fmt.Errorf("key '%s' given more than once", args[i])
+					}
+					raw[args[i]] = args[i+1]
+					keys = append(keys, args[i])
+				}
+				warnDeprecated(cfg, keys...)
+				if err := cfg.SetStrings(ctx, raw); err != nil {
+					return err
+				}
+				for _, key := range keys {
+					if err := printKey(cfg, key); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "edit",
+			Usage: "change several keys at once in $EDITOR",
+			Description: "Opens the config as commented JSON in $VISUAL, $EDITOR or vi. When the editor closes, every value is checked\n" +
+				"against the schema, on errors the editor reopens with them inline. Changed keys are applied in one transaction.",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				original, err := cfg.EditDocument()
+				if err != nil {
+					return err
+				}
+				doc := original
+				for {
+					edited, err := editFile(ctx, doc)
+					if err != nil {
+						return err
+					}
+					if len(bytes.TrimSpace(edited)) == 0 || bytes.Equal(edited, doc) {
+						fmt.Println("Edit cancelled, no changes made.")
+						return nil
+					}
+					changed, err := cfg.ApplyEdit(ctx, original, edited)
+					var editErrs config.EditErrors
+					if errors.As(err, &editErrs) {
+						fmt.Fprintf(os.Stderr, "%s\nReopening the editor, close it without changes to give up.\n", err)
+						doc = config.AnnotateEdit(edited, editErrs)
+						continue
+					}
+					if err != nil {
+						return err
+					}
+					if len(changed) == 0 {
+						fmt.Println("No values changed.")
+						return nil
+					}
+					for _, key := range changed {
+						if err := printKey(cfg, key); err != nil {
+							return err
+						}
+					}
+					return nil
+				}
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "print all keys and their values",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				return cfg.Print()
+			},
+		},
+		{
+			Name:      "describe",
+			Usage:     "explain what a key, or every key, does",
+			ArgsUsage: "[KEY]",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "markdown",
+					Usage: "print the markdown reference of every key, as found in docs/config.md",
+				},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				if cmd.Bool("markdown") {
+					if cmd.NArg() != 0 {
+						return fmt.Errorf("--markdown does not take a KEY")
+					}
+					return cfg.WriteReference(os.Stdout)
+				}
+				if cmd.NArg() > 1 {
+					return fmt.Errorf("expected at most one argument: KEY")
+				}
+				keys := cfg.Keys()
+				if cmd.NArg() == 1 {
+					keys = []string{cmd.Args().First()}
+					warnDeprecated(cfg, keys...)
+				}
+				for i, key := range keys {
+					info, err := cfg.Describe(key)
+					if err != nil {
+						return err
+					}
+					if i > 0 {
+						fmt.Println()
+					}
+					fmt.Printf("%s (%s)\n", info.Key, info.Type)
+					if info.Description != "" {
+						fmt.Printf("  %s\n", info.Description)
+					}
+					if !info.Internal {
+						if info.DefaultDoc != "" {
+							fmt.Printf("  default: %s (%s)\n", config.Format(info.Default), info.DefaultDoc)
+						} else {
+							fmt.Printf("  default: %s\n", config.Format(info.Default))
+						}
+						fmt.Printf("  env:     %s\n", info.EnvName)
+					}
+					for _, note := range info.Notes() {
+						fmt.Printf("  %s\n", note)
+					}
+					if info.Managed {
+						fmt.Printf("  managed by %s, read-only\n", cfg.ManagedPath())
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "validate",
+			Usage: "check the stored config and env overrides against the schema's rules",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				if err := cfg.Validate(); err != nil {
+					return err
+				}
+				fmt.Println("Config is valid.")
+				return nil
+			},
+		},
+		{
+			Name:  "doctor",
+			Usage: "find stored keys that don't fit the schema and offer to repair them",
+			Description: "Lists unknown keys (e.g. left behind by a migration), values that can't be decoded and keys missing from the database.\n" +
+				"Unknown keys are deleted, broken or missing values are reset to their defaults and broken profile overrides are removed.",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				problems, err := cfg.Doctor()
+				if err != nil {
+					return err
+				}
+				if len(problems) == 0 {
+					fmt.Println("No problems found.")
+					return nil
+				}
+				for _, p := range problems {
+					fmt.Printf("%s -> %s\n", p, p.Fix())
+				}
+				if err := confirm(cmd, fmt.Sprintf("Repair %d problem(s)?", len(problems))); err != nil {
+					return err
+				}
+				fixed, err := cfg.Repair(ctx)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Repaired %d problem(s), run `config validate` to check the result.\n", len(fixed))
+				return nil
+			},
+		},
+		{
+			Name:  "migrate",
+			Usage: "migrate the stored config to this binary's schema version",
+			Description: "Other commands migrate automatically on startup, this one lets you preview it with --dry-run.\n" +
+				"The database is copied to " + config.BackupDirName + "/pre-migrate-<from>-<to> in the data directory before migrating.",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "print the keys that would be added, removed or rewritten without changing anything",
+				},
+				&cli.BoolFlag{
+					Name:  "prune",
+					Usage: "delete stored keys that aren't part of this binary's schema",
+					Value: config.PruneUnknownKeys,
+				},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				cfg.PruneUnknown = cmd.Bool("prune")
+				if !cmd.Bool("dry-run") {
+					if err := cfg.Migrate(ctx); err != nil {
+						return err
+					}
+					fmt.Printf("Config is at version %s.\n", cfg.Version)
+					return nil
+				}
+				plan, err := cfg.PlanMigration(ctx)
+				if err != nil {
+					return err
+				}
+				switch {
+				case plan.From == "":
+					fmt.Printf("Config is not initialized yet, it would be created with version %s.\n", plan.To)
+				case plan.From == plan.To && len(plan.Removed) == 0:
+					fmt.Printf("Config is up to date (version %s).\n", plan.To)
+					return nil
+				case plan.From == plan.To:
+					fmt.Printf("Config is at version %s, unknown keys would be pruned.\n", plan.To)
+				default:
+					fmt.Printf("Config would be migrated from %s to %s: %s\n", plan.From, plan.To, strings.Join(plan.Steps, ", "))
+				}
+				for _, section := range []struct {
+					name string
+					keys []string
+				}{{"added", plan.Added}, {"removed", plan.Removed}, {"rewritten", plan.Rewritten}} {
+					if len(section.keys) > 0 {
+						fmt.Printf("  %s: %s\n", section.name, strings.Join(section.keys, ", "))
+					}
+				}
+				fmt.Println("Dry run, nothing was changed.")
+				return nil
+			},
+		},
+		{
+			Name:  "verify",
+			Usage: "check the schema record, version and migrations of this binary fit together",
+			Description: "Meant for development, run it (or configtest.Verify in a test) after changing the schema.\n" +
+				"Prints the key changes between adjacent schema versions.",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				for _, d := range cfg.SchemaDiffs() {
+					fmt.Println(d)
+				}
+				if err := cfg.Verify(); err != nil {
+					return fmt.Errorf("schema verification failed:\n%w", err)
+				}
+				fmt.Printf("Schema record is consistent, current version %s.\n", cfg.Version)
+				return nil
+			},
+		},
+		{
+			Name:      "scaffold",
+			Usage:     "print a migration function for the key changes between two schema versions",
+			ArgsUsage: "FROM TO",
+			Description: "Meant for development: add the new schema (e.g. config.SchemaOf[schemaV1_1_0]()) to SchemaRecord, run this\n" +
+				"and paste the output into go/database/config/migration.go. Renames are migrated, other changes are left as TODOs.",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				if cmd.NArg() != 2 {
+					return fmt.Errorf("expected exactly two arguments: FROM TO")
+				}
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				diff, err := cfg.SchemaDiff(cmd.Args().Get(0), cmd.Args().Get(1))
+				if err != nil {
+					return err
+				}
+				src, err := cfg.ScaffoldMigration(diff.From, diff.To)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(os.Stderr, diff)
+				fmt.Print(src)
+				return nil
+			},
+		},
+		{
+			Name:  "export",
+			Usage: "write the stored config as a versioned JSON document",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "file",
+					Usage: "write to `PATH` instead of stdout",
+				},
+				&cli.BoolFlag{
+					Name:  "include-sensitive",
+					Usage: "include sensitive values in plaintext, they're left out by default",
+				},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				doc, err := cfg.Export(cmd.Bool("include-sensitive"))
+				if err != nil {
+					return err
+				}
+				data, err := json.MarshalIndent(doc, "", "  ")
+				if err != nil {
+					return err
+				}
+				data = append(data, '\n')
+				if path := cmd.String("file"); path != "" {
+					if err := os.WriteFile(path, data, 0600); err != nil {
+						return fmt.Errorf("failed to write export: %w", err)
+					}
+					fmt.Printf("Config version %s exported to %s\n", doc.Version, path)
+					return nil
+				}
+				_, err = os.Stdout.Write(data)
+				return err
+			},
+		},
+		{
+			Name:        "import",
+			Usage:       "apply a document written by export",
+			ArgsUsage:   "FILE",
+			Description: "Older documents are migrated to the current schema, sensitive keys they leave out keep their stored value. The whole document is applied in one transaction. Use - to read from stdin.",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				if cmd.NArg() != 1 {
+					return fmt.Errorf("expected exactly one argument: FILE")
+				}
+				path := cmd.Args().First()
+				var data []byte
+				if path == "-" {
+					data, err = io.ReadAll(os.Stdin)
+				} else {
+					data, err = os.ReadFile(path)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to read import: %w", err)
+				}
+				var doc config.Document
+				if err := json.Unmarshal(data, &doc); err != nil {
+					return fmt.Errorf("failed to parse import: %w", err)
+				}
+				if err := cfg.Import(ctx, &doc); err != nil {
+					return fmt.Errorf("import failed, no changes were made: %w", err)
+				}
+				fmt.Printf("Imported %d keys from config version %s\n", len(doc.Values), doc.Version)
+				return nil
+			},
+		},
+		{
+			Name:      "history",
+			Usage:     "print recent config changes, newest first",
+			ArgsUsage: "[KEY]",
+			Flags: []cli.Flag{
+				&cli.IntFlag{
+					Name:  "limit",
+					Usage: "print at most `N` entries, 0 for all",
+					Value: 20,
+				},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				if cmd.NArg() > 1 {
+					return fmt.Errorf("expected at most one argument: KEY")
+				}
+				key := cmd.Args().First()
+				if key != "" {
+					warnDeprecated(cfg, key)
+					if _, err := cfg.Describe(key); err != nil {
+						return err
+					}
+				}
+				entries, err := cfg.History(key, int(cmd.Int("limit")))
+				if err != nil {
+					return err
+				}
+				if len(entries) == 0 {
+					fmt.Println("No config changes recorded.")
+					return nil
+				}
+				for _, e := range entries {
+					version := e.Version
+					if version == "" {
+						version = "dev"
+					}
+					key := e.Key
+					if e.Profile != "" {
+						key = e.Profile + "/" + e.Key
+					}
+					fmt.Printf("#%d  %s  %s  %s  %s: %s -> %s\n", e.ID, e.Time.Local().Format(time.DateTime), e.Source, version,
+						key, cfg.HistoryValue(e.Key, e.Old), cfg.HistoryValue(e.Key, e.New))
+				}
+				return nil
+			},
+		},
+		{
+			Name:      "revert",
+			Usage:     "undo a change from the history",
+			ArgsUsage: "ENTRY_ID",
+			Description: "Sets the key of the entry back to its value before that change. Later changes of the key are overwritten,\n" +
+				"the revert itself is recorded as a new entry.",
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				if cmd.NArg() != 1 {
+					return fmt.Errorf("expected exactly one argument: ENTRY_ID")
+				}
+				id, err := strconv.ParseUint(strings.TrimPrefix(cmd.Args().First(), "#"), 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid entry ID '%s'", cmd.Args().First())
+				}
+				entry, err := cfg.Revert(ctx, id)
+				if err != nil {
+					return err
+				}
+				if entry.Profile != cfg.ActiveProfile() {
+					fmt.Printf("Reverted #%d, %s restored in profile '%s'.\n", entry.ID, entry.Key, entry.Profile)
+					return nil
+				}
+				return printKey(cfg, entry.Key)
+			},
+		},
+		{
+			Name:  "profile",
+			Usage: "manage named config profiles, select one with --profile NAME or " + config.ProfileEnv,
+			Description: "Profiles are sparse overlays of the base config. With one active, `config get|set|reset|list`\n" +
+				"read through it (env -> profile -> base -> default) and write to it.",
+			Commands: []*cli.Command{
+				{
+					Name:  "list",
+					Usage: "print every profile and the keys it overrides",
+					Action: func(ctx context.Context, cmd *cli.Command) error {
+						cfg, err := configFromContext(ctx)
+						if err != nil {
+							return err
+						}
+						profiles, err := cfg.Profiles()
+						if err != nil {
+							return err
+						}
+						if len(profiles) == 0 {
+							fmt.Println("No profiles.")
+							return nil
+						}
+						for _, p := range profiles {
+							active := ""
+							if p.Name == cfg.ActiveProfile() {
+								active = " (active)"
+							}
+							fmt.Printf("%s%s: %s\n", p.Name, active, strings.Join(p.Keys, ", "))
+						}
+						return nil
+					},
+				},
+				{
+					Name:      "create",
+					Usage:     "create an empty profile",
+					ArgsUsage: "NAME",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "from",
+							Usage: "copy the overrides of profile `NAME`",
+						},
+					},
+					Action: func(ctx context.Context, cmd *cli.Command) error {
+						cfg, err := configFromContext(ctx)
+						if err != nil {
+							return err
+						}
+						if cmd.NArg() != 1 {
+							return fmt.Errorf("expected exactly one argument: NAME")
+						}
+						name := cmd.Args().First()
+						if err := cfg.CreateProfile(ctx, name, cmd.String("from")); err != nil {
+							return err
+						}
+						fmt.Printf("Profile '%s' created, use it with --profile %s or %s=%s\n", name, name, config.ProfileEnv, name)
+						return nil
+					},
+				},
+				{
+					Name:      "diff",
+					Usage:     "print the keys that differ between a profile and the base config, or another profile",
+					ArgsUsage: "NAME [OTHER]",
+					Action: func(ctx context.Context, cmd *cli.Command) error {
+						cfg, err := configFromContext(ctx)
+						if err != nil {
+							return err
+						}
+						if cmd.NArg() < 1 || cmd.NArg() > 2 {
+							return fmt.Errorf("expected NAME and optionally OTHER")
+						}
+						a, b := cmd.Args().Get(0), cmd.Args().Get(1)
+						diffs, err := cfg.DiffProfiles(a, b)
+						if err != nil {
+							return err
+						}
+						if b == "" {
+							b = "base"
+						}
+						if len(diffs) == 0 {
+							fmt.Printf("No differences between %s and %s.\n", a, b)
+							return nil
+						}
+						for _, d := range diffs {
+							fmt.Printf("%s: %s (%s) vs %s (%s)\n", d.Key, config.Format(d.A), a, config.Format(d.B), b)
+						}
+						return nil
+					},
+				},
+				{
+					Name:      "delete",
+					Usage:     "delete a profile and its overrides",
+					ArgsUsage: "NAME",
+					Action: func(ctx context.Context, cmd *cli.Command) error {
+						cfg, err := configFromContext(ctx)
+						if err != nil {
+							return err
+						}
+						if cmd.NArg() != 1 {
+							return fmt.Errorf("expected exactly one argument: NAME")
+						}
+						name := cmd.Args().First()
+						if err := confirm(cmd, fmt.Sprintf("Delete profile '%s'?", name)); err != nil {
+							return err
+						}
+						if err := cfg.DeleteProfile(ctx, name); err != nil {
+							return err
+						}
+						fmt.Printf("Profile '%s' deleted.\n", name)
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:      "reset",
+			Usage:     "restore a key, or every key, to its default value",
+			ArgsUsage: "KEY|--all",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "all",
+					Usage: "reset every key",
+				},
+			},
+			Action: func(ctx context.Context, cmd *cli.Command) error {
+				cfg, err := configFromContext(ctx)
+				if err != nil {
+					return err
+				}
+				if cmd.Bool("all") {
+					if cmd.NArg() != 0 {
+						return fmt.Errorf("--all does not take a KEY")
+					}
+					if err := confirm(cmd, "Reset every config key to its default value?"); err != nil {
+						return err
+					}
+					if err := cfg.Reset(ctx); err != nil {
+						return err
+					}
+					fmt.Println("All config keys reset to defaults.")
+					return nil
+				}
+				if cmd.NArg() != 1 {
+					return fmt.Errorf("expected exactly one argument: KEY (or --all)")
+				}
+				key := cmd.Args().First()
+				warnDeprecated(cfg, key)
+				if err := cfg.Reset(ctx, key); err != nil {
+					return err
+				}
+				return printKey(cfg, key)
+			},
+		},
+	},
+}
+
+// editFile writes doc to a temporary file, opens it in the user's editor and returns what was saved.
+// The file is private to the user and removed afterwards, it may hold config values.
+func editFile(ctx context.Context, doc []byte) ([]byte, error) {
+	f, err := os.CreateTemp("", "goweb-config-*.jsonc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(doc); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := append(strings.Fields(editor), f.Name()) // e.g. EDITOR="code --wait"
+	c := exec.CommandContext(ctx, args[0], args[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("editor '%s' failed: %w", editor, err)
+	}
+	return os.ReadFile(f.Name())
+}
+
+func configFromContext(ctx context.Context) (*config.Config, error) {
+	cfg := config.FromContext(ctx)
+	if cfg == nil {
+		return nil, fmt.Errorf("config not found in context")
+	}
+	return cfg, nil
+}
+
+// warnDeprecated prints a warning for every key given by an old name or that is deprecated, scripts should be updated.
+func warnDeprecated(cfg *config.Config, keys ...string) {
+	for _, key := range keys {
+		if msg := cfg.Deprecation(key); msg != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+		}
+	}
+}
+
+// printKey prints the effective value of a key after a write, warning if an env var shadows it.
+func printKey(cfg *config.Config, key string) error {
+	val, src, err := cfg.Lookup(key)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %s\n", key, config.Format(cfg.Redact(key, val)))
+	root := config.PathKey(key)
+	if src == config.SourceEnv {
+		fmt.Printf("Note: %s is set, it overrides the stored value.\n", config.EnvName(root))
+	}
+	if info, err := cfg.Describe(root); err == nil && info.NeedsRestart {
+		fmt.Printf("Note: a running service restarts its HTTP server to apply %s.\n", root)
+	}
+	return nil
+}
+
+// errDeclined is returned by confirm when the answer is no, so the command exits non-zero without changing anything.
+var errDeclined = errors.New("aborted, nothing was changed")
+
+// confirm asks a yes/no question unless --yes was given and returns nil for yes. It refuses to ask when stdin
+// isn't a terminal, and running out of input (e.g. `< /dev/null`) counts as no, so scripts can't hang on a prompt.
+func confirm(cmd *cli.Command, question string) error {
+	if cmd.Bool("yes") {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("stdin is not a terminal, pass --yes to answer yes to: %s", question)
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("%s (y/n): ", question)
+		input, err := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(input)) {
+		case "y", "yes":
+			return nil
+		case "n", "no":
+			return errDeclined
+		}
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+			return errDeclined
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read answer: %w", err)
+		}
+		fmt.Println("Please answer 'y' or 'n'.")
+	}
+}
@@ -3,12 +3,12 @@ package commands
 import (
 	"context"
 	"fmt"
+	"goweb/go/database/config"
 	"goweb/go/database/datapath"
 	"goweb/go/server"
 	"goweb/go/update"
 	"net/http"
 
-	"github.com/Data-Corruption/stdx/xhttp"
 	"github.com/Data-Corruption/stdx/xlog"
 	"github.com/Data-Corruption/stdx/xnet"
 	"github.com/urfave/cli/v3"
@@ -56,7 +56,17 @@ var Service = &cli.Command{
 					return fmt.Errorf("failed to wait for network: %w", err)
 				}
 
-				var srv *xhttp.Server
+				// config changes made by the service itself show up as such in `config history`
+				ctx = config.ChangeSourceIntoContext(ctx, "service")
+
+				// apply log level changes made while running, e.g. `goweb config set logLevel debug`
+				if err := config.OnChange(ctx, "logLevel", func(_, level string) {
+					if err := xlog.FromContext(ctx).SetLevel(level); err != nil {
+						xlog.Errorf(ctx, "failed to apply log level '%s': %s", level, err)
+					}
+				}); err != nil {
+					return fmt.Errorf("failed to watch log level: %w", err)
+				}
 
 				// hello world handler
 				mux := http.NewServeMux()
@@ -66,21 +76,14 @@ var Service = &cli.Command{
 				mux.HandleFunc("/update", func(w http.ResponseWriter, r *http.Request) {
 					// daemon update example. add auth ofc, etc
 					w.Write([]byte("Starting update...\n"))
+					ctx := config.ChangeSourceIntoContext(ctx, "http "+r.RemoteAddr)
 					if err := update.Update(ctx, true); err != nil {
 						xlog.Errorf(ctx, "/update update start failed: %s", err)
 					}
 				})
 
-				// create server
-				var err error
-				srv, err = server.New(ctx, mux)
-				if err != nil {
-					return fmt.Errorf("failed to create server: %w", err)
-				}
-				server.IntoContext(ctx, srv)
-
-				// start http server
-				if err := srv.Listen(); err != nil {
+				// start http server, it restarts itself when its config changes
+				if err := server.Run(ctx, mux); err != nil {
 					return fmt.Errorf("server stopped with error: %w", err)
 				} else {
 					fmt.Println("server stopped gracefully")
@@ -0,0 +1,250 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+	"github.com/Data-Corruption/lmdb-go/wrap"
+)
+
+// A Collection stores values of type T as JSON in a registered DBI, under keys of type K encoded by a [KeyEncoder]:
+//
+//	func init() {
+//		database.RegisterDBI("sessions", database.DBIOptions{Doc: "login sessions by user and creation time"})
+//	}
+//
+//	sessions, err := database.NewCollection[database.Tuple, Session](db, "sessions", database.TupleKeys)
+//	err = sessions.Put(database.Tuple{"bob", uint64(time.Now().Unix())}, Session{...})
+//	err = sessions.ScanPrefix(database.Tuple{"bob"}, func(key database.Tuple, s Session) error { ... })
+//
+// The plain methods run their own transaction. The Tx ones take one, to combine several operations (or
+// collections) atomically in a db.Update, or to read a consistent snapshot in a db.View. Don't call the plain
+// ones inside an Update, writes run on a single goroutine so a nested one deadlocks.
+
+// ErrStopScan can be returned by a scan callback to stop early, the scan then returns nil.
+var ErrStopScan = errors.New("stop scan")
+
+// Collection is a typed view of a DBI, see [NewCollection].
+type Collection[K, T any] struct {
+	DB   *wrap.DB
+	DBI  lmdb.DBI
+	name string
+	keys KeyEncoder[K]
+}
+
+// NewCollection returns the collection stored in the DBI name, which has to be registered with [RegisterDBI].
+func NewCollection[K, T any](db *wrap.DB, name string, keys KeyEncoder[K]) (*Collection[K, T], error) {
+	dbi, ok := db.GetDBis()[name]
+	if !ok {
+		return nil, fmt.Errorf("DBI '%s' not found in DB, register it with database.RegisterDBI", name)
+	}
+	return &Collection[K, T]{DB: db, DBI: dbi, name: name, keys: keys}, nil
+}
+
+// Get returns the value stored under key, lmdb.IsNotFound(err) is true if there's none.
+func (c *Collection[K, T]) Get(key K) (val T, err error) {
+	err = c.DB.View(func(txn *lmdb.Txn) error {
+		val, err = c.TxGet(txn, key)
+		return err
+	})
+	return val, err
+}
+
+// TxGet is [Collection.Get] within txn.
+func (c *Collection[K, T]) TxGet(txn *lmdb.Txn, key K) (T, error) {
+	var val T
+	k, err := c.encode(key)
+	if err != nil {
+		return val, err
+	}
+	data, err := txn.Get(c.DBI, k)
+	if err != nil {
+		if lmdb.IsNotFound(err) {
+			return val, err // unwrapped, lmdb.IsNotFound doesn't unwrap
+		}
+		return val, fmt.Errorf("failed to read key '%v' of '%s': %w", key, c.name, err)
+	}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return val, fmt.Errorf("failed to decode key '%v' of '%s': %w", key, c.name, err)
+	}
+	return val, nil
+}
+
+// Exists reports whether a value is stored under key.
+func (c *Collection[K, T]) Exists(key K) (ok bool, err error) {
+	err = c.DB.View(func(txn *lmdb.Txn) error {
+		ok, err = c.TxExists(txn, key)
+		return err
+	})
+	return ok, err
+}
+
+// TxExists is [Collection.Exists] within txn.
+func (c *Collection[K, T]) TxExists(txn *lmdb.Txn, key K) (bool, error) {
+	k, err := c.encode(key)
+	if err != nil {
+		return false, err
+	}
+	if _, err := txn.Get(c.DBI, k); err != nil {
+		if lmdb.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read key '%v' of '%s': %w", key, c.name, err)
+	}
+	return true, nil
+}
+
+// Put stores val under key, replacing what was there.
+func (c *Collection[K, T]) Put(key K, val T) error {
+	return c.DB.Update(func(txn *lmdb.Txn) error { return c.TxPut(txn, key, val) })
+}
+
+// TxPut is [Collection.Put] within txn.
+func (c *Collection[K, T]) TxPut(txn *lmdb.Txn, key K, val T) error {
+	k, err := c.encode(key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("failed to encode key '%v' of '%s': %w", key, c.name, err)
+	}
+	if err := txn.Put(c.DBI, k, data, 0); err != nil {
+		return fmt.Errorf("failed to write key '%v' of '%s': %w", key, c.name, err)
+	}
+	return nil
+}
+
+// Delete removes the value stored under key, deleting a missing key isn't an error.
+func (c *Collection[K, T]) Delete(key K) error {
+	return c.DB.Update(func(txn *lmdb.Txn) error { return c.TxDelete(txn, key) })
+}
+
+// TxDelete is [Collection.Delete] within txn.
+func (c *Collection[K, T]) TxDelete(txn *lmdb.Txn, key K) error {
+	k, err := c.encode(key)
+	if err != nil {
+		return err
+	}
+	if err := txn.Del(c.DBI, k, nil); err != nil && !lmdb.IsNotFound(err) {
+		return fmt.Errorf("failed to delete key '%v' of '%s': %w", key, c.name, err)
+	}
+	return nil
+}
+
+// Count returns the number of stored values.
+func (c *Collection[K, T]) Count() (n int, err error) {
+	err = c.DB.View(func(txn *lmdb.Txn) error {
+		n, err = c.TxCount(txn)
+		return err
+	})
+	return n, err
+}
+
+// TxCount is [Collection.Count] within txn.
+func (c *Collection[K, T]) TxCount(txn *lmdb.Txn) (int, error) {
+	stat, err := txn.Stat(c.DBI)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat '%s': %w", c.name, err)
+	}
+	return int(stat.Entries), nil
+}
+
+// Scan calls fn for every value in key order, until it returns an error. [ErrStopScan] stops without one.
+// fn runs in a read transaction, use [Collection.TxScan] in an Update to write depending on what's scanned.
+func (c *Collection[K, T]) Scan(fn func(key K, val T) error) error {
+	return c.DB.View(func(txn *lmdb.Txn) error { return c.TxScan(txn, fn) })
+}
+
+// TxScan is [Collection.Scan] within txn. Don't write to the collection from fn, collect the keys and do it after.
+func (c *Collection[K, T]) TxScan(txn *lmdb.Txn, fn func(key K, val T) error) error {
+	return c.scan(txn, nil, func([]byte) bool { return true }, fn)
+}
+
+// ScanPrefix is [Collection.Scan] over the keys whose encoding starts with the encoding of prefix,
+// what that means depends on the [KeyEncoder].
+func (c *Collection[K, T]) ScanPrefix(prefix K, fn func(key K, val T) error) error {
+	return c.DB.View(func(txn *lmdb.Txn) error { return c.TxScanPrefix(txn, prefix, fn) })
+}
+
+// TxScanPrefix is [Collection.ScanPrefix] within txn.
+func (c *Collection[K, T]) TxScanPrefix(txn *lmdb.Txn, prefix K, fn func(key K, val T) error) error {
+	p, err := c.keys.Encode(prefix)
+	if err != nil {
+		return fmt.Errorf("invalid prefix '%v' for '%s': %w", prefix, c.name, err)
+	}
+	return c.scan(txn, p, func(k []byte) bool { return bytes.HasPrefix(k, p) }, fn)
+}
+
+// ScanRange is [Collection.Scan] over the keys from from (inclusive) to to (exclusive).
+func (c *Collection[K, T]) ScanRange(from, to K, fn func(key K, val T) error) error {
+	return c.DB.View(func(txn *lmdb.Txn) error { return c.TxScanRange(txn, from, to, fn) })
+}
+
+// TxScanRange is [Collection.ScanRange] within txn.
+func (c *Collection[K, T]) TxScanRange(txn *lmdb.Txn, from, to K, fn func(key K, val T) error) error {
+	start, err := c.keys.Encode(from)
+	if err != nil {
+		return fmt.Errorf("invalid range start '%v' for '%s': %w", from, c.name, err)
+	}
+	end, err := c.keys.Encode(to)
+	if err != nil {
+		return fmt.Errorf("invalid range end '%v' for '%s': %w", to, c.name, err)
+	}
+	return c.scan(txn, start, func(k []byte) bool { return bytes.Compare(k, end) < 0 }, fn)
+}
+
+// scan calls fn for every entry from the first key not less than start (the first one if it's empty) while in(key).
+func (c *Collection[K, T]) scan(txn *lmdb.Txn, start []byte, in func(k []byte) bool, fn func(key K, val T) error) error {
+	cur, err := txn.OpenCursor(c.DBI)
+	if err != nil {
+		return fmt.Errorf("failed to open cursor on '%s': %w", c.name, err)
+	}
+	defer cur.Close()
+	op := uint(lmdb.SetRange)
+	if len(start) == 0 {
+		op = lmdb.First
+	}
+	for {
+		k, data, err := cur.Get(start, nil, op)
+		start, op = nil, lmdb.Next
+		if lmdb.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to scan '%s': %w", c.name, err)
+		}
+		if !in(k) {
+			return nil
+		}
+		key, err := c.keys.Decode(k)
+		if err != nil {
+			return fmt.Errorf("failed to decode key %x of '%s': %w", k, c.name, err)
+		}
+		var val T
+		if err := json.Unmarshal(data, &val); err != nil {
+			return fmt.Errorf("failed to decode key '%v' of '%s': %w", key, c.name, err)
+		}
+		if err := fn(key, val); err != nil {
+			if errors.Is(err, ErrStopScan) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// encode encodes a key for a single entry, LMDB doesn't allow empty keys.
+func (c *Collection[K, T]) encode(key K) ([]byte, error) {
+	k, err := c.keys.Encode(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key '%v' for '%s': %w", key, c.name, err)
+	}
+	if len(k) == 0 {
+		return nil, fmt.Errorf("invalid key '%v' for '%s': %w", key, c.name, wrap.ErrEmptyKey)
+	}
+	return k, nil
+}
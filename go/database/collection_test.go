@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"goweb/go/database/datapath"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+	"github.com/Data-Corruption/lmdb-go/wrap"
+)
+
+type testRecord struct {
+	Name  string
+	Score int
+}
+
+// testDB opens the registered DBIs in a fresh environment.
+func testDB(t *testing.T) *wrap.DB {
+	t.Helper()
+	db, err := New(datapath.IntoContext(context.Background(), t.TempDir()))
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+// testCollection returns a collection in the test DBI of a fresh database, filled with records.
+func testCollection[K any](t *testing.T, keys KeyEncoder[K], records map[string]K) *Collection[K, testRecord] {
+	t.Helper()
+	c, err := NewCollection[K, testRecord](testDB(t), testDBIName, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, key := range records {
+		if err := c.Put(key, testRecord{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+// scanNames collects the record names a scan visits, in order.
+func scanNames[K any](t *testing.T, scan func(fn func(K, testRecord) error) error) []string {
+	t.Helper()
+	var names []string
+	if err := scan(func(_ K, r testRecord) error {
+		names = append(names, r.Name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestCollectionPutGetDelete(t *testing.T) {
+	c := testCollection(t, StringKeys, nil)
+	tests := []struct {
+		name string
+		op   func() error
+		key  string
+		want *testRecord // nil if the key mustn't exist afterwards
+	}{
+		{name: "put", op: func() error { return c.Put("bob", testRecord{"bob", 1}) }, key: "bob", want: &testRecord{"bob", 1}},
+		{name: "replace", op: func() error { return c.Put("bob", testRecord{"bob", 2}) }, key: "bob", want: &testRecord{"bob", 2}},
+		{name: "delete", op: func() error { return c.Delete("bob") }, key: "bob"},
+		{name: "delete missing", op: func() error { return c.Delete("bob") }, key: "bob"},
+		{name: "tx put", op: func() error {
+			return c.DB.Update(func(txn *lmdb.Txn) error { return c.TxPut(txn, "alice", testRecord{"alice", 3}) })
+		}, key: "alice", want: &testRecord{"alice", 3}},
+		{name: "tx delete", op: func() error {
+			return c.DB.Update(func(txn *lmdb.Txn) error { return c.TxDelete(txn, "alice") })
+		}, key: "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.op(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.Get(tt.key)
+			ok, existsErr := c.Exists(tt.key)
+			if existsErr != nil {
+				t.Fatal(existsErr)
+			}
+			if tt.want == nil {
+				if !lmdb.IsNotFound(err) || ok {
+					t.Fatalf("got %v, %v, exists %t, want not found", got, err, ok)
+				}
+				return
+			}
+			if err != nil || got != *tt.want || !ok {
+				t.Fatalf("got %v, %v, exists %t, want %v", got, err, ok, *tt.want)
+			}
+			var txGot testRecord
+			if err := c.DB.View(func(txn *lmdb.Txn) (err error) {
+				txGot, err = c.TxGet(txn, tt.key)
+				return err
+			}); err != nil || txGot != *tt.want {
+				t.Fatalf("TxGet got %v, %v, want %v", txGot, err, *tt.want)
+			}
+		})
+	}
+
+	if err := c.Put("", testRecord{}); !errors.Is(err, wrap.ErrEmptyKey) {
+		t.Fatalf("put of an empty key: got %v, want %v", err, wrap.ErrEmptyKey)
+	}
+}
+
+func TestCollectionTxAtomic(t *testing.T) {
+	c := testCollection(t, StringKeys, map[string]string{"a": "a"})
+	failed := errors.New("failed")
+	err := c.DB.Update(func(txn *lmdb.Txn) error {
+		if err := c.TxPut(txn, "b", testRecord{Name: "b"}); err != nil {
+			return err
+		}
+		if err := c.TxDelete(txn, "a"); err != nil {
+			return err
+		}
+		if n, err := c.TxCount(txn); err != nil || n != 1 {
+			t.Errorf("TxCount sees %d, %v inside the txn, want 1", n, err)
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("got %v, want %v", err, failed)
+	}
+	if got := scanNames(t, c.Scan); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("got %v after an aborted txn, want [a]", got)
+	}
+}
+
+func TestCollectionScanPrefix(t *testing.T) {
+	c := testCollection(t, TupleKeys, map[string]Tuple{
+		"bob1":   {"bob", uint64(1)},
+		"bob2":   {"bob", uint64(2)},
+		"bob256": {"bob", uint64(256)},
+		"bobby":  {"bobby", uint64(1)},
+		"alice":  {"alice", uint64(1)},
+	})
+	tests := []struct {
+		prefix Tuple
+		want   []string
+	}{
+		{prefix: Tuple{"bob"}, want: []string{"bob1", "bob2", "bob256"}},
+		{prefix: Tuple{"bob", uint64(2)}, want: []string{"bob2"}},
+		{prefix: Tuple{"bobby"}, want: []string{"bobby"}},
+		{prefix: Tuple{"carol"}},
+		{prefix: Tuple{}, want: []string{"alice", "bob1", "bob2", "bob256", "bobby"}},
+	}
+	for _, tt := range tests {
+		scan := func(fn func(Tuple, testRecord) error) error { return c.ScanPrefix(tt.prefix, fn) }
+		if got := scanNames(t, scan); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("prefix %q: got %v, want %v", tt.prefix, got, tt.want)
+		}
+		txScan := func(fn func(Tuple, testRecord) error) error {
+			return c.DB.View(func(txn *lmdb.Txn) error { return c.TxScanPrefix(txn, tt.prefix, fn) })
+		}
+		if got := scanNames(t, txScan); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("prefix %q in a txn: got %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestCollectionScanRange(t *testing.T) {
+	c := testCollection(t, Uint64Keys, map[string]uint64{"1": 1, "2": 2, "255": 255, "256": 256, "1000": 1000})
+	tests := []struct {
+		from, to uint64
+		want     []string
+	}{
+		{from: 0, to: 1 << 20, want: []string{"1", "2", "255", "256", "1000"}},
+		{from: 2, to: 256, want: []string{"2", "255"}},
+		{from: 3, to: 255},
+		{from: 256, to: 257, want: []string{"256"}},
+		{from: 1000, to: 1}, // empty range
+	}
+	for _, tt := range tests {
+		scan := func(fn func(uint64, testRecord) error) error { return c.ScanRange(tt.from, tt.to, fn) }
+		if got := scanNames(t, scan); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%d, %d): got %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+		txScan := func(fn func(uint64, testRecord) error) error {
+			return c.DB.View(func(txn *lmdb.Txn) error { return c.TxScanRange(txn, tt.from, tt.to, fn) })
+		}
+		if got := scanNames(t, txScan); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%d, %d) in a txn: got %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestCollectionScanStop(t *testing.T) {
+	c := testCollection(t, Uint64Keys, map[string]uint64{"1": 1, "2": 2, "3": 3})
+	var names []string
+	err := c.Scan(func(key uint64, r testRecord) error {
+		names = append(names, r.Name)
+		if key == 2 {
+			return ErrStopScan
+		}
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(names, []string{"1", "2"}) {
+		t.Fatalf("got %v, %v, want [1 2] and no error", names, err)
+	}
+	failed := errors.New("failed")
+	if err := c.Scan(func(uint64, testRecord) error { return failed }); !errors.Is(err, failed) {
+		t.Fatalf("got %v, want %v", err, failed)
+	}
+	if n, err := c.Count(); err != nil || n != 3 {
+		t.Fatalf("Count got %d, %v, want 3", n, err)
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Data-Corruption/stdx/xlog"
+	"golang.org/x/mod/semver"
+)
+
+// A renamed key can keep its old name as an alias for a few releases, so scripts calling `config get oldName`
+// keep working. Declare it on the new key (`aliases` on a hand-written value, or `alias:"oldName@v1.4.0"` with [SchemaOf])
+// with the last schema version that still accepts it. [Get], [Set], paths, bindings, the managed file and
+// the CLI map the old name to the new one, warning through xlog once per process. Once the current schema
+// version is past Until, the old name is rejected with a hint to the new one.
+//
+// A key can also be marked deprecated with a notice (`deprecated` on a hand-written value or the `deprecated` tag),
+// using it logs the notice, e.g. "ignored since v1.3.0, set logLevel instead".
+
+// Alias is an old name of a key, accepted in its place up to and including schema version Until.
+type Alias struct {
+	Name  string
+	Until string
+}
+
+// findAlias returns the key of the current schema that name is an alias of, expired or not.
+func (cfg *Config) findAlias(name string) (string, Alias, bool) {
+	for key, v := range cfg.Schemas[cfg.Version] {
+		for _, a := range v.Aliases() {
+			if a.Name == name {
+				return key, a, true
+			}
+		}
+	}
+	return "", Alias{}, false
+}
+
+// canonical maps a key, or a path into one, given by an alias to the current key, logging a warning.
+// Deprecated keys are logged too. Unknown keys are returned as is, lookup reports them.
+func (cfg *Config) canonical(path string) (string, error) {
+	root := PathKey(path)
+	if v, ok := cfg.Schemas[cfg.Version][root]; ok {
+		if v.Deprecated() != "" {
+			cfg.warnOnce(root, "config key '%s' is deprecated: %s", root, v.Deprecated())
+		}
+		return path, nil
+	}
+	key, a, ok := cfg.findAlias(root)
+	if !ok {
+		return path, nil
+	}
+	if semver.Compare(cfg.Version, a.Until) > 0 {
+		return "", fmt.Errorf("key '%s' was renamed to '%s', the old name stopped working after %s", root, key, a.Until)
+	}
+	cfg.warnOnce(root, "config key '%s' is deprecated, use '%s' (the old name works until %s)", root, key, a.Until)
+	return key + strings.TrimPrefix(path, root), nil
+}
+
+// Deprecation returns the warning for using key (or a path into it), empty if there's none. For the CLI, the
+// library logs it anyway.
+func (cfg *Config) Deprecation(path string) string {
+	root := PathKey(path)
+	if v, ok := cfg.Schemas[cfg.Version][root]; ok {
+		if v.Deprecated() != "" {
+			return fmt.Sprintf("'%s' is deprecated: %s", root, v.Deprecated())
+		}
+		return ""
+	}
+	if key, a, ok := cfg.findAlias(root); ok && semver.Compare(cfg.Version, a.Until) <= 0 {
+		return fmt.Sprintf("'%s' was renamed to '%s', the old name works until %s", root, key, a.Until)
+	}
+	return ""
+}
+
+// warnOnce logs a warning the first time it's given for name, Get may be called for it on every request.
+func (cfg *Config) warnOnce(name string, format string, args ...any) {
+	if _, warned := cfg.warned.LoadOrStore(name, true); !warned {
+		xlog.Warnf(cfg.context(), format, args...)
+	}
+}
+
+// aliasNames returns key and the names of its aliases, e.g. to find history entries recorded under an old name.
+func (cfg *Config) aliasNames(key string) []string {
+	names := []string{key}
+	if v, ok := cfg.Schemas[cfg.Version][key]; ok {
+		for _, a := range v.Aliases() {
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}
+
+// parseAliases parses an `alias` tag, e.g. "oldName@v1.4.0,older@v1.2.0".
+func parseAliases(tag string) ([]Alias, error) {
+	var aliases []Alias
+	for _, part := range strings.Split(tag, ",") {
+		name, until, ok := strings.Cut(strings.TrimSpace(part), "@")
+		if !ok || name == "" || !semver.IsValid(until) {
+			return nil, fmt.Errorf("alias '%s' must be NAME@VERSION, e.g. oldName@v1.4.0", part)
+		}
+		aliases = append(aliases, Alias{Name: name, Until: until})
+	}
+	return aliases, nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"goweb/go/database"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// BackupDirName is the directory in the data path backups are written to, e.g. `~/.goweb/backups`.
+//
+// To roll back a bad migration, stop the service and every other instance, then copy `data.mdb` of the
+// backup over `~/.goweb/db/data.mdb` and delete `~/.goweb/db/lock.mdb`. The backup only works with the
+// same `config.key` and a binary whose schema version is the backup's (or has a migration from it).
+const BackupDirName = "backups"
+
+// Backup copies the LMDB environment to `<data path>/backups/<name>/data.mdb` (compacted, see [database.Copy])
+// and returns that directory. A timestamp is appended to name if the directory already exists, earlier backups are
+// never overwritten. Nothing is copied if the Config has no data path (see [New]), the returned directory is empty then.
+//
+// The copy is made from a read txn, so it's a consistent snapshot of the last commit. Writes made earlier in txn
+// are not part of it.
+func (cfg *Config) Backup(txn *lmdb.Txn, name string) (string, error) {
+	if cfg.dataPath == "" {
+		return "", nil
+	}
+	dir := filepath.Join(cfg.dataPath, BackupDirName, name)
+	if _, err := os.Stat(dir); err == nil {
+		dir += "-" + time.Now().Format("20060102-150405")
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := database.Copy(cfg.dataPath, dir); err != nil {
+		return "", fmt.Errorf("failed to copy database: %w", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestMigrateBacksUp(t *testing.T) {
+	type v1_0_0 struct {
+		Version string `cfg:"version,internal" default:"v1.0.0"`
+		Port    int    `cfg:"port" default:"8080"`
+	}
+	type v1_1_0 struct {
+		Version string `cfg:"version,internal" default:"v1.1.0"`
+		Port    int    `cfg:"port" default:"8080"`
+	}
+	schemas := map[string]schema{"v1.0.0": SchemaOf[v1_0_0](), "v1.1.0": SchemaOf[v1_1_0]()}
+	migrations := map[string]MigrationFunc{
+		"v1.0.0->v1.1.0": func(txn *lmdb.Txn, dbi lmdb.DBI, schemas map[string]schema) error { return nil },
+	}
+	ctx, db := testDB(t)
+	old := openConfig(t, ctx, db, "v1.0.0", schemas, nil)
+	if err := Set(IntoContext(ctx, old), "port", 9000); err != nil {
+		t.Fatal(err)
+	}
+	openConfig(t, ctx, db, "v1.1.0", schemas, migrations) // migrates, backing up first
+
+	dir := filepath.Join(old.dataPath, BackupDirName, "pre-migrate-v1.0.0-v1.1.0")
+	if _, err := os.Stat(filepath.Join(dir, "data.mdb")); err != nil {
+		t.Fatalf("no backup: %s", err)
+	}
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Close()
+	if err := env.SetMaxDBs(8); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Open(dir, lmdb.Readonly, 0600); err != nil {
+		t.Fatalf("backup can't be opened: %s", err)
+	}
+	got := map[string]any{}
+	if err := env.View(func(txn *lmdb.Txn) error {
+		dbi, err := txn.OpenDBI("config", 0)
+		if err != nil {
+			return err
+		}
+		for _, key := range []string{"version", "port"} {
+			data, err := txn.Get(dbi, []byte(key))
+			if err != nil {
+				return err
+			}
+			var v any
+			if err := json.Unmarshal(data, &v); err != nil {
+				return err
+			}
+			got[key] = v
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got["version"] != "v1.0.0" || got["port"] != float64(9000) {
+		t.Errorf("backup holds %v, want the config as of before the migration", got)
+	}
+}
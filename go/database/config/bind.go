@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// Binding structs map exported fields to config keys with a `cfg:"key"` tag, untagged fields are ignored.
+//
+//	type settings struct {
+//		Port   int  `cfg:"port"`
+//		UseTLS bool `cfg:"useTLS"`
+//	}
+//
+//	var _ = config.Register[settings]() // checked against the schema by Init
+//
+//	s, err := config.Load[settings](ctx)
+
+var (
+	bindingsMu sync.Mutex
+	bindings   []reflect.Type
+)
+
+// Register records T so [Init] can check its tags against the current schema, failing at startup
+// instead of on first use. Meant to be called from a package level var, it returns true so it can be.
+func Register[T any]() bool {
+	bindingsMu.Lock()
+	defer bindingsMu.Unlock()
+	bindings = append(bindings, reflect.TypeFor[T]())
+	return true
+}
+
+// Load fills a T from its tagged fields in a single read transaction. Values resolve like [Get].
+func Load[T any](ctx context.Context) (T, error) {
+	var result T
+	cfg := FromContext(ctx)
+	if cfg == nil {
+		return result, fmt.Errorf("config not found in context")
+	}
+	fields, err := cfg.bindingFields(reflect.TypeFor[T]())
+	if err != nil {
+		return result, err
+	}
+	rv := reflect.ValueOf(&result).Elem()
+	err = cfg.DB.View(func(txn *lmdb.Txn) error {
+		for key, index := range fields {
+			val, _, err := cfg.resolveCached(txn, key, cfg.Schemas[cfg.Version][key])
+			if err != nil {
+				return fmt.Errorf("failed to get config key '%s': %w", key, err)
+			}
+			rv.FieldByIndex(index).Set(reflect.ValueOf(val))
+		}
+		return nil
+	})
+	if err != nil {
+		return *new(T), err
+	}
+	return result, nil
+}
+
+// TaggedKeys returns the config keys T's fields are bound to, in field order.
+func TaggedKeys[T any]() []string {
+	var keys []string
+	t := reflect.TypeFor[T]()
+	for i := 0; i < t.NumField(); i++ {
+		if key, _, ok := tagKey(t.Field(i)); ok && key != "-" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// checkBindings verifies every registered binding struct against the current schema.
+func (cfg *Config) checkBindings() error {
+	bindingsMu.Lock()
+	defer bindingsMu.Unlock()
+	var errs []error
+	for _, t := range bindings {
+		if _, err := cfg.bindingFields(t); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// bindingFields maps each tagged field of t to its config key, checking the key exists
+// in the current schema with exactly the field's type.
+func (cfg *Config) bindingFields(t reflect.Type) (map[string][]int, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config binding %s must be a struct", t)
+	}
+	fields := map[string][]int{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, _, ok := tagKey(f) // options are for SchemaOf, so a schema struct can be loaded too
+		if !ok || key == "-" {
+			continue
+		}
+		if !f.IsExported() {
+			return nil, fmt.Errorf("config binding %s: field %s must be exported", t, f.Name)
+		}
+		key, err := cfg.canonical(key)
+		if err != nil {
+			return nil, fmt.Errorf("config binding %s: field %s: %w", t, f.Name, err)
+		}
+		v, err := cfg.lookup(key)
+		if err != nil {
+			return nil, fmt.Errorf("config binding %s: field %s: %w", t, f.Name, err)
+		}
+		if v.Type() != f.Type {
+			return nil, fmt.Errorf("config binding %s: field %s is %s but key '%s' is %s", t, f.Name, f.Type, key, v.Type())
+		}
+		if _, dup := fields[key]; dup {
+			return nil, fmt.Errorf("config binding %s: key '%s' is bound more than once", t, key)
+		}
+		fields[key] = f.Index
+	}
+	return fields, nil
+}
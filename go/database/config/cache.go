@@ -0,0 +1,216 @@
+package config
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// Reads through [View] (so [Get], [GetPath], [Load]) are served from a cache of decoded values, skipping the
+// JSON decode / decryption of the stored value. Every config write (see update in `history.go`) bumps a
+// generation counter in the config meta DBI, in the same txn, so a read only has to compare it to the
+// generation the cache was filled at to notice writes from any process. The counter is only read once per
+// LMDB txn ID (which moves with every commit to the env), reads of a snapshot already checked skip it.
+// See `cache_test.go` for benchmarks.
+//
+// Reads inside [Update] bypass the cache, they must see the txn's own writes. Env overrides aren't cached, they're
+// checked first as before. Values holding slices, maps or pointers are copied on the way out, so callers may modify them.
+
+var generationKey = []byte("generation")
+
+// valueCache holds decoded stored values (profile overrides, base values or defaults) of one generation.
+type valueCache struct {
+	mu      sync.RWMutex
+	gen     uint64
+	txnID   uintptr // newest txn ID gen was read at, txns with this ID see the same generation
+	entries map[cacheKey]cacheEntry
+	envs    map[string]string // key -> env var name, they're fixed
+}
+
+type cacheKey struct{ profile, key string }
+
+type cacheEntry struct {
+	val   any
+	src   Source
+	clone bool // val holds references, see cloneValue
+}
+
+// generation returns the config generation as of txn, 0 if nothing was written yet.
+func (cfg *Config) generation(txn *lmdb.Txn) (uint64, error) {
+	data, err := txn.Get(cfg.MetaDBI, generationKey)
+	if lmdb.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read config generation: %w", err)
+	}
+	if len(data) != 8 {
+		return 0, fmt.Errorf("corrupt config generation")
+	}
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// bumpGeneration invalidates the caches of every process once txn commits.
+func (cfg *Config) bumpGeneration(txn *lmdb.Txn) error {
+	gen, err := cfg.generation(txn)
+	if err != nil {
+		return err
+	}
+	if err := txn.Put(cfg.MetaDBI, generationKey, binary.BigEndian.AppendUint64(nil, gen+1), 0); err != nil {
+		return fmt.Errorf("failed to write config generation: %w", err)
+	}
+	return nil
+}
+
+// resolveCached is resolve for read-only txns, serving stored values from the cache.
+func (cfg *Config) resolveCached(txn *lmdb.Txn, key string, v *value) (any, Source, error) {
+	if cfg.DisableCache {
+		return cfg.resolve(txn, key, v)
+	}
+	if _, ok := os.LookupEnv(cfg.cache.envName(key)); ok && !v.IsInternal() {
+		return cfg.resolve(txn, key, v)
+	}
+	id := txn.ID()
+	ck := cacheKey{cfg.profile, key}
+	cfg.cache.mu.RLock()
+	gen, checked := cfg.cache.gen, id != 0 && cfg.cache.txnID == id
+	e, ok := cfg.cache.entries[ck]
+	cfg.cache.mu.RUnlock()
+	if checked && ok {
+		return e.get(), e.src, nil
+	}
+	if !checked {
+		var err error
+		if gen, err = cfg.generation(txn); err != nil {
+			return nil, "", err
+		}
+		cfg.cache.mu.Lock()
+		if gen == cfg.cache.gen && id > cfg.cache.txnID {
+			cfg.cache.txnID = id
+		}
+		e, ok = cfg.cache.entries[ck]
+		ok = ok && cfg.cache.gen == gen
+		cfg.cache.mu.Unlock()
+		if ok {
+			return e.get(), e.src, nil
+		}
+	}
+
+	val, src, err := cfg.stored(txn, key, v)
+	if err != nil {
+		return nil, src, err
+	}
+	e = cacheEntry{val: val, src: src, clone: hasRefs(v.Type())}
+	cfg.cache.mu.Lock()
+	// generations only grow, a reader on an older snapshot mustn't throw away newer entries
+	if gen > cfg.cache.gen || cfg.cache.entries == nil {
+		cfg.cache.gen, cfg.cache.txnID, cfg.cache.entries = gen, id, map[cacheKey]cacheEntry{}
+	}
+	if gen == cfg.cache.gen {
+		cfg.cache.entries[ck] = e
+	}
+	cfg.cache.mu.Unlock()
+	return e.get(), src, nil
+}
+
+func (c *valueCache) envName(key string) string {
+	c.mu.RLock()
+	name, ok := c.envs[key]
+	c.mu.RUnlock()
+	if ok {
+		return name
+	}
+	name = EnvName(key)
+	c.mu.Lock()
+	if c.envs == nil {
+		c.envs = map[string]string{}
+	}
+	c.envs[key] = name
+	c.mu.Unlock()
+	return name
+}
+
+func (e cacheEntry) get() any {
+	if !e.clone || e.val == nil {
+		return e.val
+	}
+	return cloneValue(reflect.ValueOf(e.val)).Interface()
+}
+
+// hasRefs reports whether values of t share memory when copied, i.e. contain slices, maps, pointers or interfaces.
+func hasRefs(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Pointer, reflect.Interface:
+		return true
+	case reflect.Array:
+		return hasRefs(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasRefs(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cloneValue deep copies the exported parts of v, which is everything encoding/json fills in.
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for it := v.MapRange(); it.Next(); {
+			c.SetMapIndex(it.Key(), cloneValue(it.Value()))
+		}
+		return c
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Array, reflect.Struct:
+		if !hasRefs(v.Type()) {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		if v.Kind() == reflect.Array {
+			for i := 0; i < v.Len(); i++ {
+				c.Index(i).Set(cloneValue(v.Index(i)))
+			}
+			return c
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(cloneValue(v.Field(i)))
+			}
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// benchmarkGet reads a few keys per iteration, like a request handler would.
+func benchmarkGet(b *testing.B, disableCache bool) {
+	cfg := testConfig(b, Version, SchemaRecord, Migrations)
+	cfg.DisableCache = disableCache
+	ctx := IntoContext(cfg.ctx, cfg)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Get[string](ctx, "logLevel"); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := Get[int](ctx, "port"); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := Get[bool](ctx, "updateNotify"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetCached(b *testing.B) { benchmarkGet(b, false) }
+
+func BenchmarkGetUncached(b *testing.B) { benchmarkGet(b, true) }
+
+func TestGenerationBumpsOnChangeOnly(t *testing.T) {
+	cfg := testConfig(t, Version, SchemaRecord, Migrations)
+	ctx := IntoContext(cfg.ctx, cfg)
+	gen := func() uint64 {
+		t.Helper()
+		var g uint64
+		if err := cfg.DB.View(func(txn *lmdb.Txn) (err error) {
+			g, err = cfg.generation(txn)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+
+	start := gen()
+	if err := cfg.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(ctx, "port", 8080); err != nil { // the default, already stored
+		t.Fatal(err)
+	}
+	if got := gen(); got != start {
+		t.Errorf("generation went from %d to %d without a change", start, got)
+	}
+	if err := Set(ctx, "port", 9000); err != nil {
+		t.Fatal(err)
+	}
+	if got := gen(); got != start+1 {
+		t.Errorf("generation is %d after a change, want %d", got, start+1)
+	}
+}
+
+func TestCacheSeesOtherWriters(t *testing.T) {
+	ctx, db := testDB(t)
+	cfg := openConfig(t, ctx, db, Version, SchemaRecord, Migrations)
+	other := openConfig(t, ctx, db, Version, SchemaRecord, Migrations) // e.g. `goweb config set` in another process
+	cfgCtx, otherCtx := IntoContext(ctx, cfg), IntoContext(ctx, other)
+	for _, port := range []int{9000, 9001} {
+		if got, err := Get[int](cfgCtx, "port"); err != nil || got == port { // fills the cache
+			t.Fatalf("got %d, %v before the write", got, err)
+		}
+		if err := Set(otherCtx, "port", port); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := Get[int](cfgCtx, "port"); err != nil || got != port {
+			t.Fatalf("got %d, %v after another writer set it, want %d", got, err, port)
+		}
+	}
+}
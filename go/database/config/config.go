@@ -8,13 +8,33 @@
 //	// Set a config value (type-safe)
 //	err := config.Set[int](ctx, "port", 9000)
 //
-//	// See [Migrate]in `config.go` for a txn example
+//	// Change several keys atomically, see `tx.go`
+//	err := config.Update(ctx, func(tx *config.Tx) error {
+//		if err := config.TxSet(tx, "tlsKeyPath", keyPath); err != nil {
+//			return err
+//		}
+//		if err := config.TxSet(tx, "tlsCertPath", certPath); err != nil {
+//			return err
+//		}
+//		return config.TxSet(tx, "useTLS", true)
+//	})
+//
+//	// Read / write part of a struct, list or map key, see `path.go` (cors is hypothetical, no such key yet)
+//	origins, err := config.GetPath[[]string](ctx, "cors.origins")
+//	err := config.SetPath(ctx, "cors.origins[1]", "https://x")
+//
+//	// See [Migrate]in `config.go` for a raw txn example
+//
+// From the shell, keys can be inspected and changed with `goweb config get|set|list|reset`,
+// `goweb config describe` explains them using the metadata on each schema value.
 //
 // Modifying the Schema:
 //
-//  1. Copy the current schema to a new version in SchemaRecord in `schema.go`.
-//  2. Update the new schema with your changes.
-//  3. Add migration functions in `migration.go` to handle the transition from the old schemas to the new one.
+//  1. Copy the current schema struct in `schema.go` to a new version and add it to SchemaRecord with [SchemaOf] (see `struct.go`).
+//  2. Update the new struct with your changes. Attach per-key validators with [Checks] and cross-key rules to RuleRecord (see `validate.go`).
+//  3. Add migration functions in `migration.go` to handle the transition from the old schemas to the new one,
+//     `goweb config scaffold OLD NEW` prints a starting point.
+//  4. Run [Verify] (`goweb config verify`, or `configtest.Verify(t)` in a test) to check the three fit together.
 //
 // see `migration.go` for example / details. This config impl may seem strange, this is due to me wanting a no compromise system that:
 //
@@ -25,52 +45,102 @@ package config
 
 import (
 	"context"
+	"crypto/cipher"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"goweb/go/database"
+	"goweb/go/database/datapath"
 	"goweb/go/database/helpers"
+	"goweb/go/version"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/Data-Corruption/lmdb-go/lmdb"
 	"github.com/Data-Corruption/lmdb-go/wrap"
+	"github.com/Data-Corruption/stdx/xlog"
+	"golang.org/x/mod/semver"
 )
 
-type valueInterface interface {
-	DefaultValue() any
-	GetAny(string, *wrap.DB) (any, error)
-	SetAny(string, *wrap.DB, any) error
+// value is a key of a schema: its type, default and metadata. Schemas are derived from structs, see [SchemaOf].
+type value struct {
+	field      string                        // Go field name, used to detect renamed keys, see SchemaDiff
+	t          reflect.Type                  // type of the key's values
+	d          any                           // default value
+	df         func(ctx context.Context) any // computes the default when it's used instead of d, see `defaults.go`
+	dfDoc      string                        // how df derives it for docs, e.g. "<data path>/tls/cert.pem"
+	checks     []func(any) error             // run before every write, see `validate.go`
+	sensitive  bool                          // encrypted at rest and redacted when displayed, see `sensitive.go`
+	desc       string                        // one line shown by `config describe`
+	unit       string                        // e.g. "seconds", empty if the value has none
+	internal   bool                          // managed by the app, can't be set / reset / imported / overridden by users
+	restart    bool                          // the service restarts (at least its HTTP server) to apply a change
+	aliases    []Alias                       // old names still accepted for the key, see `alias.go`
+	deprecated string                        // logged when the key is used, empty if it isn't deprecated
 }
 
-type value[T any] struct {
-	d T // default value
+// DefaultValue returns the default of the key, computed from ctx if the value has a default func.
+func (v *value) DefaultValue(ctx context.Context) any {
+	if v.df != nil {
+		return v.df(ctx)
+	}
+	return v.d
 }
 
-func (v *value[T]) DefaultValue() any { return v.d }
+func (v *value) DefaultDoc() string { return v.dfDoc }
 
-func (v *value[T]) GetAny(key string, db *wrap.DB) (any, error) {
-	data, err := db.Read(database.ConfigDBIName, []byte(key))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config key '%s': %w", key, err)
-	}
+func (v *value) IsSensitive() bool { return v.sensitive }
+
+func (v *value) Description() string { return v.desc }
+
+func (v *value) Unit() string { return v.unit }
+
+func (v *value) IsInternal() bool { return v.internal }
+
+func (v *value) NeedsRestart() bool { return v.restart }
+
+func (v *value) Aliases() []Alias { return v.aliases }
+
+func (v *value) Deprecated() string { return v.deprecated }
+
+// Decode unmarshals raw stored data into the key's type.
+func (v *value) Decode(key string, data []byte) (any, error) {
 	// Safeguard against unexpected empty data from storage (e.g., corruption, non-JSON write).
-	// Treat as unset and return the default value; json.Marshal doesn't produce empty []byte for standard types.
+	// json.Marshal doesn't produce empty []byte for standard types.
 	if len(data) == 0 {
 		return nil, fmt.Errorf("config key '%s' has unexpected empty value in storage", key)
 	}
-	var result T
-	if err := json.Unmarshal(data, &result); err != nil {
+	ptr := reflect.New(v.t)
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
 		return nil, fmt.Errorf("unmarshal error for key '%s': %w", key, err)
 	}
-	return result, nil
+	return ptr.Elem().Interface(), nil
 }
 
-func (v *value[T]) SetAny(key string, db *wrap.DB, val any) error {
-	data, err := json.Marshal(val)
+// Parse converts user input (e.g. from the command line) into the key's type.
+// Strings are taken verbatim so they don't need shell quoting, everything else is parsed as JSON.
+func (v *value) Parse(raw string) (any, error) {
+	parsed, err := parseAs(v.t, raw)
 	if err != nil {
-		return fmt.Errorf("marshal error for key '%s': %w", key, err)
+		return nil, err
 	}
-	return db.Write(database.ConfigDBIName, []byte(key), data) // update wrapper pkg to allow direct dbi use
+	return parsed.Interface(), nil
 }
 
+// TypeName returns the Go type name of the key, e.g. "int" or "config.Example".
+func (v *value) TypeName() string { return v.t.String() }
+
+func (v *value) Type() reflect.Type { return v.t }
+
+// ErrNewerConfig is returned by Migrate when the stored config comes from a newer release
+// and no down-migration path to the current version is registered.
+var ErrNewerConfig = errors.New("config is from a newer version")
+
 type ctxKey struct{}
 
 func IntoContext(ctx context.Context, config *Config) context.Context {
@@ -88,25 +158,69 @@ type Config struct {
 	Version    string
 	Schemas    map[string]schema
 	Migrations map[string]MigrationFunc // Key: "fromVersion->toVersion"
+	Rules      map[string][]Rule        // Key: version, cross-key checks for that schema
 	DB         *wrap.DB
-	DBI        lmdb.DBI // cached DBI for config
+	DBI        lmdb.DBI        // cached DBI for config
+	HistoryDBI lmdb.DBI        // cached DBI for the change history, see `history.go`
+	ProfileDBI lmdb.DBI        // cached DBI for profile overlays, see `profile.go`
+	MetaDBI    lmdb.DBI        // cached DBI for the generation counter, see `cache.go`
+	profile    string          // active profile, empty for the base config
+	dataPath   string          // pre-migration backups go here, none are made if empty
+	out        io.Writer       // migration progress, stdout if nil
+	ctx        context.Context // Init's ctx, passed to default funcs where no other ctx is at hand
+	binVersion string          // recorded in the history, empty in dev builds
+	aead       cipher.AEAD     // encrypts sensitive values, see LoadKey
+	watch      watcher         // change notifications, see `watch.go`
+	cache      valueCache      // decoded values, see `cache.go`
+	warned     sync.Map        // aliases and deprecated keys already warned about, see `alias.go`
+	managed    managedFile     // keys set by the managed file, see `managed.go`
+
+	PruneUnknown bool // Migrate deletes keys that aren't part of the current schema, defaults to PruneUnknownKeys, see `doctor.go`
+	DisableCache bool // read every value from the db, e.g. to compare in benchmarks, see `cache.go`
+}
+
+func init() {
+	database.RegisterDBI(database.ConfigDBIName, database.DBIOptions{Doc: "config values of the base config"})
+	database.RegisterDBI(database.ConfigHistoryDBIName, database.DBIOptions{Doc: "config change log"})
+	database.RegisterDBI(database.ConfigProfilesDBIName, database.DBIOptions{Doc: "config profile overrides"})
+	database.RegisterDBI(database.ConfigMetaDBIName, database.DBIOptions{Doc: "config bookkeeping"})
 }
 
-func New(version string, schemas map[string]schema, migrations map[string]MigrationFunc, db *wrap.DB) (*Config, error) { // separate from init for testing
+func New(version string, schemas map[string]schema, migrations map[string]MigrationFunc, rules map[string][]Rule, db *wrap.DB) (*Config, error) { // separate from init for testing
 	dbi, ok := db.GetDBis()[database.ConfigDBIName]
 	if !ok {
 		return nil, fmt.Errorf("config DBI not found in DB")
 	}
+	historyDBI, ok := db.GetDBis()[database.ConfigHistoryDBIName]
+	if !ok {
+		return nil, fmt.Errorf("config history DBI not found in DB")
+	}
+	profileDBI, ok := db.GetDBis()[database.ConfigProfilesDBIName]
+	if !ok {
+		return nil, fmt.Errorf("config profiles DBI not found in DB")
+	}
+	metaDBI, ok := db.GetDBis()[database.ConfigMetaDBIName]
+	if !ok {
+		return nil, fmt.Errorf("config meta DBI not found in DB")
+	}
 	return &Config{
 		Version:    version,
 		Schemas:    schemas,
 		Migrations: migrations,
+		Rules:      rules,
 		DB:         db,
 		DBI:        dbi,
+		HistoryDBI: historyDBI,
+		ProfileDBI: profileDBI,
+		MetaDBI:    metaDBI,
+
+		PruneUnknown: PruneUnknownKeys,
 	}, nil
 }
 
-func Init(ctx context.Context) (context.Context, error) {
+// Init creates the config and puts it into ctx. If migrate is false the stored config is left as is,
+// e.g. for `config migrate --dry-run`, reading keys may then fail until it's migrated.
+func Init(ctx context.Context, migrate bool) (context.Context, error) {
 	if FromContext(ctx) != nil {
 		return ctx, fmt.Errorf("config already initialized in context")
 	}
@@ -114,130 +228,448 @@ func Init(ctx context.Context) (context.Context, error) {
 	if db == nil {
 		return nil, fmt.Errorf("database not initialized in context")
 	}
-	config, err := New(Version, SchemaRecord, Migrations, db)
+	dataPath := datapath.FromContext(ctx)
+	if dataPath == "" {
+		return nil, fmt.Errorf("data path not set before config initialization")
+	}
+	config, err := New(Version, SchemaRecord, Migrations, RuleRecord, db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create config: %w", err)
 	}
-	if err := config.Migrate(); err != nil {
-		return nil, fmt.Errorf("failed to migrate config: %w", err)
+	config.binVersion = version.FromContext(ctx)
+	config.dataPath = dataPath
+	config.ctx = ctx
+	if err := config.LoadKey(filepath.Join(dataPath, KeyFileName)); err != nil {
+		return nil, fmt.Errorf("failed to load config key: %w", err)
+	}
+	if err := config.loadManaged(filepath.Join(dataPath, ManagedFileName)); err != nil {
+		return nil, fmt.Errorf("failed to load managed config: %w", err)
+	}
+	if migrate {
+		if err := config.Migrate(ctx); err != nil {
+			return nil, fmt.Errorf("failed to migrate config: %w", err)
+		}
+	}
+	if err := config.checkBindings(); err != nil {
+		return nil, fmt.Errorf("config bindings don't match the schema: %w", err)
+	}
+	if name := os.Getenv(ProfileEnv); name != "" {
+		// a stale env var must not lock the user out of the config, e.g. of `config profile create` for it
+		if err := config.UseProfile(name); err != nil {
+			msg := fmt.Sprintf("ignoring %s, using the base config: %s", ProfileEnv, err)
+			xlog.Warn(ctx, msg)
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+		}
 	}
 	return IntoContext(ctx, config), nil
 }
 
 func Get[T any](ctx context.Context, key string) (T, error) {
-	cfg := FromContext(ctx)
-	if cfg == nil {
-		return *new(T), fmt.Errorf("config not found in context")
-	}
-	// Ensure the schema exists for the current version.
-	cfgValue, exists := cfg.Schemas[cfg.Version][key]
-	if !exists {
-		return *new(T), fmt.Errorf("key %s not found in config", key)
+	var result T
+	if err := View(ctx, func(tx *Tx) (err error) {
+		result, err = TxGet[T](tx, key)
+		return err
+	}); err != nil {
+		return *new(T), fmt.Errorf("failed to get config key '%s': %w", key, err)
 	}
-	// Assert that the schema definition is of the expected type.
-	typedValue, ok := cfgValue.(*value[T])
-	if !ok {
-		return *new(T), fmt.Errorf("type mismatch for key %s", key)
+	return result, nil
+}
+
+func Set[T any](ctx context.Context, key string, val T) error {
+	if err := Update(ctx, func(tx *Tx) error {
+		return TxSet(tx, key, val)
+	}); err != nil {
+		return fmt.Errorf("failed to set config key '%s': %w", key, err)
 	}
-	// Use the GetAny method to retrieve the value.
-	rawValue, err := typedValue.GetAny(key, cfg.DB)
+	return nil
+}
+
+// typed returns the schema definition of a key, asserting it's of type T.
+func typed[T any](cfg *Config, key string) (*value, error) {
+	v, err := cfg.lookup(key)
 	if err != nil {
-		return *new(T), fmt.Errorf("failed to get config key '%s': %w", key, err)
+		return nil, err
 	}
-	// Assert that the retrieved value is of the expected type.
-	result, ok := rawValue.(T)
-	if !ok {
-		return *new(T), fmt.Errorf("stored value for key %s is not of expected type", key)
+	if v.Type() != reflect.TypeFor[T]() {
+		return nil, fmt.Errorf("type mismatch for key %s: schema declares %s, got %s", key, v.TypeName(), reflect.TypeFor[T]())
 	}
-	return result, nil
+	return v, nil
 }
 
-func Set[T any](ctx context.Context, key string, val T) error {
-	cfg := FromContext(ctx)
-	if cfg == nil {
-		return fmt.Errorf("config not found in context")
+// Migrate migrates or initializes the configuration in the database, then applies the managed file (see `managed.go`)
+// in the same txn. Before migrating, the LMDB environment is copied to `<data path>/backups`, see [Config.Backup].
+// Default funcs of the schema values are called with ctx.
+func (cfg *Config) Migrate(ctx context.Context) error {
+	return cfg.updateSteps(
+		changeStep{MigrationChangeSource, func(txn *lmdb.Txn) error { return cfg.migrate(ctx, txn, true) }},
+		changeStep{ManagedChangeSource, func(txn *lmdb.Txn) error { return cfg.reconcileManaged(ctx, txn) }},
+	)
+}
+
+// migrate is the body of [Config.Migrate], split out so it can run as part of a larger txn, e.g. [Config.Import].
+// backup is false where the result is thrown away or replaces the stored config anyway (dry runs, imports).
+func (cfg *Config) migrate(ctx context.Context, txn *lmdb.Txn, backup bool) error {
+	var discVersion string
+	if err := helpers.GetAndUnmarshal(txn, cfg.DBI, []byte("version"), &discVersion); err != nil {
+		if !lmdb.IsNotFound(err) {
+			return fmt.Errorf("failed to get config version: %w", err)
+		}
+		// no version found, initialize config
+		for key, value := range cfg.Schemas[cfg.Version] {
+			defaultValue := value.DefaultValue(ctx)
+			if err := cfg.put(txn, key, value, defaultValue); err != nil {
+				return fmt.Errorf("failed to write initial value for key '%s': %w", key, err)
+			}
+		}
+		if err := cfg.validateTxn(txn); err != nil {
+			return fmt.Errorf("default config is invalid: %w", err)
+		}
+		cfg.printf("config initialized with version '%s'\n", cfg.Version)
+		return nil
 	}
-	// Ensure the schema exists for the current version.
-	schemaForVersion, ok := cfg.Schemas[cfg.Version]
-	if !ok {
-		return fmt.Errorf("schema for version %s not found", cfg.Version)
+
+	// check if version is the latest
+	if discVersion == cfg.Version {
+		if cfg.PruneUnknown {
+			return cfg.pruneUnknownLogged(txn)
+		}
+		return nil
 	}
-	// Retrieve the schema definition for the key.
-	schemaVal, exists := schemaForVersion[key]
-	if !exists {
-		return fmt.Errorf("key %s not found in config", key)
+
+	if !semver.IsValid(discVersion) {
+		return fmt.Errorf("stored config version '%s' is not a valid semver version", discVersion)
 	}
-	// Assert that the schema definition is of the expected type.
-	typedValue, ok := schemaVal.(*value[T])
-	if !ok {
-		return fmt.Errorf("type mismatch for key %s", key)
+
+	// migrate config, one registered step at a time
+	path, err := cfg.migrationPath(discVersion, cfg.Version)
+	if err != nil {
+		// the db was written by a newer release, e.g. the installer rolled back the binary after a failed update
+		if semver.Compare(discVersion, cfg.Version) > 0 {
+			return fmt.Errorf("%w: stored config is version '%s' but this binary only knows up to '%s' and has no down-migration for it. Run '%s update' to install a release that supports it",
+				ErrNewerConfig, discVersion, cfg.Version, filepath.Base(os.Args[0]))
+		}
+		return err
 	}
-	// Use the SetAny method to set the value.
-	if err := typedValue.SetAny(key, cfg.DB, val); err != nil {
-		return fmt.Errorf("failed to set config key '%s': %w", key, err)
+	cfg.printf("config migration: %s -> %s (%d steps)\n", discVersion, cfg.Version, len(path))
+	if backup {
+		dir, err := cfg.Backup(txn, fmt.Sprintf("pre-migrate-%s-%s", discVersion, cfg.Version))
+		if err != nil {
+			return fmt.Errorf("failed to back up the database before migrating, nothing was changed: %w", err)
+		}
+		if dir != "" {
+			cfg.printf("config migration: database backed up to %s\n", dir)
+		}
+	}
+	for _, step := range path {
+		_, to, _ := strings.Cut(step, "->")
+		cfg.printf("config migration step: %s\n", step)
+		if err := cfg.Migrations[step](txn, cfg.DBI, cfg.Schemas); err != nil {
+			return fmt.Errorf("migration %s failed: %w", step, err)
+		}
+		if err := helpers.MarshalAndPut(txn, cfg.DBI, []byte("version"), to); err != nil {
+			return fmt.Errorf("failed to write new version '%s': %w", to, err)
+		}
+	}
+	// keys added by the new schema that no migration step wrote
+	for key, value := range cfg.Schemas[cfg.Version] {
+		if _, err := txn.Get(cfg.DBI, []byte(key)); !lmdb.IsNotFound(err) {
+			continue
+		}
+		if err := cfg.put(txn, key, value, value.DefaultValue(ctx)); err != nil {
+			return fmt.Errorf("failed to write default value for key '%s': %w", key, err)
+		}
+	}
+	if err := cfg.pruneProfiles(txn); err != nil {
+		return err
 	}
+	if cfg.PruneUnknown {
+		if err := cfg.pruneUnknownLogged(txn); err != nil {
+			return err
+		}
+	}
+	if err := cfg.validateTxn(txn); err != nil {
+		return fmt.Errorf("migrated config is invalid: %w", err)
+	}
+	cfg.printf("config migration successful: %s -> %s\n", discVersion, cfg.Version)
 	return nil
 }
 
-// Migrate migrates or initializes the configuration in the database.
-func (cfg *Config) Migrate() error {
-	return cfg.DB.Update(func(txn *lmdb.Txn) error {
-		var discVersion string
-		if err := helpers.GetAndUnmarshal(txn, cfg.DBI, []byte("version"), &discVersion); err != nil {
-			if !lmdb.IsNotFound(err) {
-				return fmt.Errorf("failed to get config version: %w", err)
+// Keys returns the keys of the current schema in sorted order.
+func (cfg *Config) Keys() []string {
+	keys := make([]string, 0, len(cfg.Schemas[cfg.Version]))
+	for key := range cfg.Schemas[cfg.Version] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TypeName returns the declared type of a key in the current schema.
+func (cfg *Config) TypeName(key string) (string, error) {
+	v, err := cfg.lookup(key)
+	if err != nil {
+		return "", err
+	}
+	return v.TypeName(), nil
+}
+
+// Lookup returns the effective value of a key, or of a path inside one (see `path.go`), without requiring
+// its type at compile time, along with the layer it was resolved from.
+func (cfg *Config) Lookup(path string) (any, Source, error) {
+	path, err := cfg.canonical(path)
+	if err != nil {
+		return nil, "", err
+	}
+	key, segs, err := splitPath(path)
+	if err != nil {
+		return nil, "", err
+	}
+	var val any
+	var src Source
+	err = cfg.DB.View(func(txn *lmdb.Txn) error {
+		val, src, err = (&Tx{cfg: cfg, txn: txn, readOnly: true}).resolvePath(key, segs)
+		return err
+	})
+	return val, src, err
+}
+
+// SetStrings parses each raw value using the declared type of its key and stores them all in a single transaction.
+// Keys may be paths into a key (see `path.go`), the value is then parsed as the type found at the path.
+// Internal and managed keys are rejected, this is meant for user input.
+func (cfg *Config) SetStrings(ctx context.Context, raw map[string]string) error {
+	updates := make(map[string]any, len(raw))
+	var paths []pathUpdate
+	for path, r := range raw {
+		path, err := cfg.canonical(path)
+		if err != nil {
+			return err
+		}
+		key, segs, err := splitPath(path)
+		if err != nil {
+			return err
+		}
+		v, err := cfg.lookup(key)
+		if err != nil {
+			return err
+		}
+		if v.IsInternal() {
+			return fmt.Errorf("key '%s' is internal and can't be set by hand", key)
+		}
+		if err := cfg.checkManaged(key); err != nil {
+			return err
+		}
+		if len(segs) > 0 {
+			t, err := leafType(v.Type(), segs)
+			if err != nil {
+				return fmt.Errorf("invalid path '%s': %w", path, err)
 			}
-			// no version found, initialize config
-			for key, value := range cfg.Schemas[cfg.Version] {
-				defaultValue := value.DefaultValue()
-				if err := helpers.MarshalAndPut(txn, cfg.DBI, []byte(key), defaultValue); err != nil {
-					return fmt.Errorf("failed to write initial value for key '%s': %w", key, err)
-				}
+			leaf, err := parseAs(t, r)
+			if err != nil {
+				return fmt.Errorf("invalid value for '%s': %w", path, err)
 			}
-			fmt.Printf("config initialized with version '%s'\n", cfg.Version)
-			return nil
+			paths = append(paths, pathUpdate{path: path, key: key, segs: segs, leaf: leaf})
+			continue
 		}
-
-		// check if version is the latest
-		if discVersion == cfg.Version {
-			return nil
+		parsed, err := v.Parse(r)
+		if err != nil {
+			return fmt.Errorf("invalid value for key '%s': %w", key, err)
 		}
-
-		// migrate config
-		migratePath := discVersion + "->" + cfg.Version
-		fmt.Printf("config migration: %s\n", migratePath)
-		if migrationFunc, ok := cfg.Migrations[migratePath]; ok {
-			if err := migrationFunc(txn, cfg.DBI, cfg.Schemas); err != nil {
-				return fmt.Errorf("migration failed: %w", err)
+		updates[key] = parsed
+	}
+	sortPathUpdates(paths)
+	return cfg.update(changeSource(ctx), func(txn *lmdb.Txn) error {
+		// paths apply on top of a whole-key update given in the same call, or on top of each other
+		for _, p := range paths {
+			val, err := cfg.withPath(txn, p.key, p.segs, p.leaf, updates[p.key])
+			if err != nil {
+				return err
 			}
-			if err := helpers.MarshalAndPut(txn, cfg.DBI, []byte("version"), cfg.Version); err != nil {
-				return fmt.Errorf("failed to write new version '%s': %w", cfg.Version, err)
+			updates[p.key] = val
+		}
+		return cfg.write(txn, updates)
+	})
+}
+
+// Reset restores the given keys to their default values in a single transaction.
+// If no keys are given, every key in the current schema that isn't internal or managed is reset.
+// With a profile active, the keys' overrides are removed from it instead.
+func (cfg *Config) Reset(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		for _, key := range cfg.Keys() {
+			if !cfg.Schemas[cfg.Version][key].IsInternal() && !cfg.IsManaged(key) {
+				keys = append(keys, key)
 			}
-			fmt.Printf("config migration successful: %s\n", migratePath)
-			return nil
 		}
-		// migration function not found
-		return fmt.Errorf("unsupported migration path: from '%s' to '%s'. No migration function registered for this transition", discVersion, cfg.Version)
+	}
+	for i, key := range keys {
+		key, err := cfg.canonical(key)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+		v, err := cfg.lookup(key)
+		if err != nil {
+			return err
+		}
+		if v.IsInternal() {
+			return fmt.Errorf("key '%s' is internal and can't be reset by hand", key)
+		}
+		if err := cfg.checkManaged(key); err != nil {
+			return err
+		}
+	}
+	if cfg.profile != "" {
+		// in a profile, resetting means falling back to the base config
+		return cfg.update(changeSource(ctx), func(txn *lmdb.Txn) error {
+			for _, key := range keys {
+				if err := txn.Del(cfg.ProfileDBI, profileKey(cfg.profile, key), nil); err != nil && !lmdb.IsNotFound(err) {
+					return fmt.Errorf("failed to reset key '%s': %w", key, err)
+				}
+			}
+			return cfg.checkRulesTxn(txn)
+		})
+	}
+	updates := make(map[string]any, len(keys))
+	for _, key := range keys {
+		updates[key] = cfg.Schemas[cfg.Version][key].DefaultValue(ctx)
+	}
+	return cfg.update(changeSource(ctx), func(txn *lmdb.Txn) error {
+		return cfg.write(txn, updates)
 	})
 }
 
+// write validates and stores updates in txn. Per-key validators run on each update, cross-key
+// rules run on the stored config with all updates applied, so related keys can change together.
+func (cfg *Config) write(txn *lmdb.Txn, updates map[string]any) error {
+	for key, val := range updates {
+		if err := cfg.set(txn, key, val); err != nil {
+			return err
+		}
+	}
+	return cfg.checkRulesTxn(txn)
+}
+
+// set validates a single value and stores it. Cross-key rules are not checked, see checkRulesTxn.
+func (cfg *Config) set(txn *lmdb.Txn, key string, val any) error {
+	v, err := cfg.lookup(key)
+	if err != nil {
+		return err
+	}
+	if err := v.Validate(val); err != nil {
+		return fmt.Errorf("invalid value for key '%s': %w", key, err)
+	}
+	if err := cfg.put(txn, key, v, val); err != nil {
+		return fmt.Errorf("failed to write key '%s': %w", key, err)
+	}
+	return nil
+}
+
+// checkRulesTxn runs the cross-key rules over the config stored in txn.
+func (cfg *Config) checkRulesTxn(txn *lmdb.Txn) error {
+	values, err := cfg.storedValues(txn)
+	if err != nil {
+		return err
+	}
+	return cfg.checkRules(values)
+}
+
+// put encodes val (encrypting it if sensitive) and stores it without validation,
+// in the active profile if there is one. Internal keys always go to the base config.
+func (cfg *Config) put(txn *lmdb.Txn, key string, v *value, val any) error {
+	return cfg.putAs(txn, cfg.profile, key, v, val)
+}
+
+// putAs is like put but writes to the given profile, "" being the base config.
+func (cfg *Config) putAs(txn *lmdb.Txn, profile string, key string, v *value, val any) error {
+	data, err := cfg.encode(key, v, val)
+	if err != nil {
+		return err
+	}
+	if profile != "" && !v.IsInternal() {
+		return txn.Put(cfg.ProfileDBI, profileKey(profile, key), data, 0)
+	}
+	return txn.Put(cfg.DBI, []byte(key), data, 0)
+}
+
+// validateTxn validates the config stored in txn.
+func (cfg *Config) validateTxn(txn *lmdb.Txn) error {
+	values, err := cfg.storedValues(txn)
+	if err != nil {
+		return err
+	}
+	return cfg.validateAll(values)
+}
+
+// lookup returns the schema definition of a key in the current version.
+func (cfg *Config) lookup(key string) (*value, error) {
+	schemaForVersion, ok := cfg.Schemas[cfg.Version]
+	if !ok {
+		return nil, fmt.Errorf("schema for version %s not found", cfg.Version)
+	}
+	v, exists := schemaForVersion[key]
+	if !exists {
+		return nil, fmt.Errorf("key %s not found in config", key)
+	}
+	return v, nil
+}
+
+// Format renders a config value for display. Strings are printed as is, everything else as JSON.
+func Format(val any) string {
+	if s, ok := val.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+	return string(data)
+}
+
 // Print prints the current configuration to stdout.
 // This is useful for debugging and verifying the current configuration state.
 func (cfg *Config) Print() error {
 	return cfg.DB.View(func(txn *lmdb.Txn) error {
-		fmt.Printf("Current Configuration (Version: %s):\n", cfg.Version)
-		for key, value := range cfg.Schemas[cfg.Version] {
-			// skip sensitive fields like this
-			// if key == "authToken" {
-			//   fmt.Printf("%s: [REDACTED]\n", key)
-			//   continue
-			// }
-			data, err := value.GetAny(key, cfg.DB)
+		if cfg.profile != "" {
+			fmt.Printf("Current Configuration (Version: %s, Profile: %s):\n", cfg.Version, cfg.profile)
+		} else {
+			fmt.Printf("Current Configuration (Version: %s):\n", cfg.Version)
+		}
+		for _, key := range cfg.Keys() {
+			data, src, err := cfg.resolve(txn, key, cfg.Schemas[cfg.Version][key])
 			if err != nil {
-				return fmt.Errorf("failed to get config key '%s': %w", key, err)
+				return err
+			}
+			data = cfg.Redact(key, data)
+			var notes []string
+			if src != SourceDB {
+				notes = append(notes, string(src))
+			}
+			if cfg.IsManaged(key) {
+				notes = append(notes, "managed")
 			}
-			fmt.Printf("%s: %v\n", key, data)
+			if len(notes) > 0 {
+				fmt.Printf("%s: %s (%s)\n", key, Format(data), strings.Join(notes, ", "))
+				continue
+			}
+			fmt.Printf("%s: %s\n", key, Format(data))
 		}
 		return nil
 	})
 }
+
+// printf writes migration progress to cfg.out, or stdout if it isn't set.
+func (cfg *Config) printf(format string, args ...any) {
+	out := cfg.out
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintf(out, format, args...)
+}
+
+// context returns Init's ctx for default funcs, or an empty one if the Config wasn't created by Init.
+func (cfg *Config) context() context.Context {
+	if cfg.ctx == nil {
+		return context.Background()
+	}
+	return cfg.ctx
+}
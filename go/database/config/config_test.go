@@ -0,0 +1,61 @@
+package config
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"goweb/go/database"
+	"goweb/go/database/datapath"
+
+	"github.com/Data-Corruption/lmdb-go/wrap"
+)
+
+// testDB opens a fresh database in a temp data directory, closed when the test ends.
+func testDB(t testing.TB) (context.Context, *wrap.DB) {
+	t.Helper()
+	ctx := datapath.IntoContext(context.Background(), t.TempDir())
+	db, err := database.New(ctx)
+	if err != nil {
+		t.Fatalf("failed to open database: %s", err)
+	}
+	t.Cleanup(db.Close)
+	return database.IntoContext(ctx, db), db
+}
+
+// testConfig returns a migrated config of the given schemas in a fresh database, version is the newest one.
+func testConfig(t testing.TB, version string, schemas map[string]schema, migrations map[string]MigrationFunc) *Config {
+	t.Helper()
+	ctx, db := testDB(t)
+	return openConfig(t, ctx, db, version, schemas, migrations)
+}
+
+// openConfig is testConfig for an existing database, e.g. to migrate it to a newer version.
+func openConfig(t testing.TB, ctx context.Context, db *wrap.DB, version string, schemas map[string]schema, migrations map[string]MigrationFunc) *Config {
+	t.Helper()
+	cfg, err := New(version, schemas, migrations, nil, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.out = io.Discard
+	cfg.ctx = ctx
+	cfg.dataPath = datapath.FromContext(ctx)
+	if err := cfg.LoadKey(filepath.Join(cfg.dataPath, KeyFileName)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// lookup returns the effective value of key, failing t on errors.
+func lookup(t testing.TB, cfg *Config, key string) any {
+	t.Helper()
+	val, _, err := cfg.Lookup(key)
+	if err != nil {
+		t.Fatalf("failed to look up '%s': %s", key, err)
+	}
+	return val
+}
@@ -0,0 +1,24 @@
+// Package configtest has test helpers for the config package.
+//
+//	func TestSchema(t *testing.T) {
+//		configtest.Verify(t)
+//	}
+package configtest
+
+import (
+	"goweb/go/database/config"
+	"testing"
+)
+
+// Verify fails t if SchemaRecord, Version, Migrations and RuleRecord don't fit together (see [config.Verify]),
+// and logs the key changes between adjacent schema versions.
+func Verify(t testing.TB) {
+	t.Helper()
+	cfg := &config.Config{Version: config.Version, Schemas: config.SchemaRecord, Migrations: config.Migrations, Rules: config.RuleRecord}
+	for _, d := range cfg.SchemaDiffs() {
+		t.Log(d)
+	}
+	if err := config.Verify(); err != nil {
+		t.Fatalf("config schema verification failed:\n%s", err)
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"context"
+	"goweb/go/database/datapath"
+	"path/filepath"
+	"time"
+)
+
+// Default funcs compute a key's default when it's used (initialization, reset, reading an unstored key)
+// rather than at package init. Set them with [DefaultFunc], along with how they're derived for the docs:
+//
+//	DefaultFunc("tlsCertPath", DataPathFile("tls", "cert.pem"), "<data path>/tls/cert.pem"),
+//
+// They must not fail, return a sensible fallback instead (the ctx may lack the data path, e.g. in [Verify]).
+
+// DataPathFile returns a default func for a path inside the data path, or "" if the data path isn't known.
+func DataPathFile(elem ...string) func(ctx context.Context) string {
+	return func(ctx context.Context) string {
+		dataPath := datapath.FromContext(ctx)
+		if dataPath == "" {
+			return ""
+		}
+		return filepath.Join(append([]string{dataPath}, elem...)...)
+	}
+}
+
+// Now is a default func for the current time in RFC 3339.
+func Now(ctx context.Context) string {
+	return time.Now().Format(time.RFC3339)
+}
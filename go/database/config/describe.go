@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeyInfo describes a key of the current schema, see [Config.Describe].
+type KeyInfo struct {
+	Key          string
+	Type         string
+	Default      any    // [Redacted] for sensitive keys
+	DefaultDoc   string // how a computed default is derived, e.g. "<data path>/tls/cert.pem", empty for static defaults
+	Description  string
+	Unit         string
+	EnvName      string // empty for internal keys, they can't be overridden
+	Sensitive    bool
+	Internal     bool
+	NeedsRestart bool
+	Aliases      []Alias
+	Deprecated   string // deprecation notice, empty if the key isn't deprecated
+	Managed      bool   // set by the managed file on this machine, see `managed.go`. Not part of Notes, those are the same everywhere
+}
+
+// Notes returns the flags of the key in a short human readable form, e.g. "internal, read-only".
+func (k KeyInfo) Notes() []string {
+	var notes []string
+	if k.Unit != "" {
+		notes = append(notes, "unit: "+k.Unit)
+	}
+	if k.Internal {
+		notes = append(notes, "internal, read-only")
+	}
+	if k.Sensitive {
+		notes = append(notes, "sensitive, encrypted at rest")
+	}
+	if k.NeedsRestart {
+		notes = append(notes, "changing it restarts the service's HTTP server")
+	}
+	if k.Deprecated != "" {
+		notes = append(notes, "deprecated: "+k.Deprecated)
+	}
+	for _, a := range k.Aliases {
+		notes = append(notes, fmt.Sprintf("formerly '%s', still accepted until %s", a.Name, a.Until))
+	}
+	return notes
+}
+
+// Describe returns the metadata of a key in the current schema.
+// An alias describes the key it stands for.
+func (cfg *Config) Describe(key string) (KeyInfo, error) {
+	key, err := cfg.canonical(key)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	v, err := cfg.lookup(key)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	info := KeyInfo{
+		Key:          key,
+		Type:         v.TypeName(),
+		Default:      cfg.Redact(key, v.DefaultValue(cfg.context())),
+		DefaultDoc:   v.DefaultDoc(),
+		Description:  v.Description(),
+		Unit:         v.Unit(),
+		Sensitive:    v.IsSensitive(),
+		Internal:     v.IsInternal(),
+		NeedsRestart: v.NeedsRestart(),
+		Managed:      cfg.IsManaged(key),
+		Aliases:      v.Aliases(),
+		Deprecated:   v.Deprecated(),
+	}
+	if !info.Internal {
+		info.EnvName = EnvName(key)
+	}
+	return info, nil
+}
+
+// WriteReference writes a markdown reference of every key in the current schema to w.
+// The checked in copy lives at `docs/config.md`, regenerate it with `goweb config describe --markdown`.
+func (cfg *Config) WriteReference(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Config Reference\n\n")
+	fmt.Fprintf(&b, "<!-- generated by `goweb config describe --markdown`, do not edit -->\n\n")
+	fmt.Fprintf(&b, "Schema version `%s`. Set keys with `goweb config set KEY VALUE` or override them with the listed environment variable.\n", cfg.Version)
+	fmt.Fprintf(&b, "Internal keys are managed by goweb and can't be changed.\n\n")
+	fmt.Fprintf(&b, "| Key | Type | Default | Env | Description | Notes |\n")
+	fmt.Fprintf(&b, "| --- | --- | --- | --- | --- | --- |\n")
+	for _, key := range cfg.Keys() {
+		info, err := cfg.Describe(key)
+		if err != nil {
+			return err
+		}
+		def := Format(info.Default)
+		if def == "" {
+			def = `""`
+		}
+		if info.DefaultDoc != "" {
+			def = info.DefaultDoc // the computed value is specific to this machine
+		}
+		def, env := "`"+def+"`", "`"+info.EnvName+"`"
+		if info.Internal {
+			def, env = "-", "-" // defaults of internal keys are runtime state, e.g. the install time
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s | %s | %s |\n",
+			info.Key, info.Type, def, env, info.Description, strings.Join(info.Notes(), "; "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// Template variables ---------------------------------------------------------
+
+// PruneUnknownKeys is the default of [Config.PruneUnknown], whether migrations delete stored keys that aren't part of the target schema.
+// Off by default so a rolled back binary doesn't lose keys of the newer release, `config doctor` can clean them up later.
+var PruneUnknownKeys = false
+
+// ----------------------------------------------------------------------------
+
+// ProblemKind is the kind of a [Problem] found by [Config.Doctor].
+type ProblemKind string
+
+const (
+	ProblemUnknown     ProblemKind = "unknown"     // stored but not part of the current schema, e.g. left behind by a migration
+	ProblemUndecodable ProblemKind = "undecodable" // stored value can't be decoded (or decrypted) as the schema's type
+	ProblemMissing     ProblemKind = "missing"     // key of the current schema that isn't stored in the base config
+)
+
+// Problem is a stored entry that doesn't fit the current schema.
+type Problem struct {
+	Kind    ProblemKind
+	Key     string
+	Profile string // empty for the base config
+	Detail  string // e.g. the decode error
+}
+
+func (p Problem) String() string {
+	s := fmt.Sprintf("%s: '%s'", p.Kind, p.Key)
+	if p.Profile != "" {
+		s += fmt.Sprintf(" in profile '%s'", p.Profile)
+	}
+	if p.Detail != "" {
+		s += ", " + p.Detail
+	}
+	return s
+}
+
+// Fix describes what [Config.Repair] does about the problem.
+func (p Problem) Fix() string {
+	switch {
+	case p.Kind == ProblemUnknown:
+		return "delete it"
+	case p.Profile != "":
+		return "remove the override, the base value applies"
+	default:
+		return "reset it to the default"
+	}
+}
+
+// Doctor returns every stored entry that doesn't fit the current schema, sorted by profile and key.
+// Missing keys are only reported for the base config, profiles are sparse.
+func (cfg *Config) Doctor() ([]Problem, error) {
+	var problems []Problem
+	err := cfg.DB.View(func(txn *lmdb.Txn) error {
+		var err error
+		problems, err = cfg.diagnose(txn)
+		return err
+	})
+	return problems, err
+}
+
+// Repair fixes every problem [Config.Doctor] finds, as described by [Problem.Fix], and returns what it fixed.
+// Cross-key rules aren't checked, run `config validate` afterwards.
+func (cfg *Config) Repair(ctx context.Context) ([]Problem, error) {
+	var problems []Problem
+	err := cfg.update(fmt.Sprintf("%s (doctor)", changeSource(ctx)), func(txn *lmdb.Txn) error {
+		var err error
+		if problems, err = cfg.diagnose(txn); err != nil {
+			return err
+		}
+		for _, p := range problems {
+			v := cfg.Schemas[cfg.Version][p.Key]
+			switch {
+			case p.Kind == ProblemUnknown || p.Profile != "":
+				k, dbi := []byte(p.Key), cfg.DBI
+				if p.Profile != "" {
+					k, dbi = profileKey(p.Profile, p.Key), cfg.ProfileDBI
+				}
+				if err := txn.Del(dbi, k, nil); err != nil && !lmdb.IsNotFound(err) {
+					return fmt.Errorf("failed to delete key '%s': %w", p.Key, err)
+				}
+			default:
+				if err := cfg.putAs(txn, "", p.Key, v, v.DefaultValue(ctx)); err != nil {
+					return fmt.Errorf("failed to reset key '%s': %w", p.Key, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return problems, nil
+}
+
+// pruneUnknown deletes keys of the base config that aren't part of the current schema and returns them.
+// Unknown profile overrides are dropped by pruneProfiles.
+func (cfg *Config) pruneUnknown(txn *lmdb.Txn) ([]string, error) {
+	var pruned []string
+	if err := cfg.eachEntry(txn, func(key string, _ []byte) error {
+		if _, ok := cfg.Schemas[cfg.Version][key]; !ok {
+			pruned = append(pruned, key)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for _, key := range pruned {
+		if err := txn.Del(cfg.DBI, []byte(key), nil); err != nil {
+			return nil, fmt.Errorf("failed to delete unknown key '%s': %w", key, err)
+		}
+	}
+	return pruned, nil
+}
+
+// eachEntry calls fn for every entry of the base config in key order, including unknown keys.
+func (cfg *Config) eachEntry(txn *lmdb.Txn, fn func(key string, data []byte) error) error {
+	cur, err := txn.OpenCursor(cfg.DBI)
+	if err != nil {
+		return fmt.Errorf("failed to open config cursor: %w", err)
+	}
+	defer cur.Close()
+	for {
+		k, v, err := cur.Get(nil, nil, lmdb.Next)
+		if lmdb.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		if err := fn(string(k), v); err != nil {
+			return err
+		}
+	}
+}
+
+func (cfg *Config) diagnose(txn *lmdb.Txn) ([]Problem, error) {
+	var problems []Problem
+	check := func(profile, key string, data []byte) {
+		v, ok := cfg.Schemas[cfg.Version][key]
+		if !ok {
+			problems = append(problems, Problem{Kind: ProblemUnknown, Key: key, Profile: profile})
+			return
+		}
+		if _, err := cfg.decode(key, v, data); err != nil {
+			problems = append(problems, Problem{Kind: ProblemUndecodable, Key: key, Profile: profile, Detail: err.Error()})
+		}
+	}
+
+	stored := map[string]bool{}
+	if err := cfg.eachEntry(txn, func(key string, data []byte) error {
+		stored[key] = true
+		check("", key, data)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := cfg.eachProfileEntry(txn, "", func(profile, key string, data []byte) error {
+		if key != "" {
+			check(profile, key, data)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for _, key := range cfg.Keys() {
+		if !stored[key] {
+			problems = append(problems, Problem{Kind: ProblemMissing, Key: key})
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].Profile != problems[j].Profile {
+			return problems[i].Profile < problems[j].Profile
+		}
+		return problems[i].Key < problems[j].Key
+	})
+	return problems, nil
+}
+
+// pruneUnknownLogged is pruneUnknown for migrations, printing what was dropped.
+func (cfg *Config) pruneUnknownLogged(txn *lmdb.Txn) error {
+	pruned, err := cfg.pruneUnknown(txn)
+	for _, key := range pruned {
+		cfg.printf("config migration: dropping unknown key '%s'\n", key)
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+	"golang.org/x/mod/semver"
+)
+
+// Document is the portable form of the config used by export / import. Values are keyed by config key.
+// The "version" key isn't part of Values, Version stamps the schema the values belong to.
+type Document struct {
+	Version string                     `json:"version"`
+	Values  map[string]json.RawMessage `json:"values"`
+}
+
+// Export returns the stored config (env overrides are not included) as a Document of the current schema version.
+// With a profile active, values are as seen through it.
+// Sensitive values are decrypted and included only if includeSensitive is set, otherwise they're left out.
+// Internal keys are machine specific state and never exported.
+func (cfg *Config) Export(includeSensitive bool) (*Document, error) {
+	doc := &Document{Version: cfg.Version, Values: map[string]json.RawMessage{}}
+	err := cfg.DB.View(func(txn *lmdb.Txn) error {
+		for _, key := range cfg.Keys() {
+			v := cfg.Schemas[cfg.Version][key]
+			if v.IsInternal() || (v.IsSensitive() && !includeSensitive) {
+				continue
+			}
+			val, _, err := cfg.stored(txn, key, v)
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(val)
+			if err != nil {
+				return fmt.Errorf("marshal error for key '%s': %w", key, err)
+			}
+			doc.Values[key] = data
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Import applies doc in a single transaction, nothing is written if any part of it fails.
+//
+// A document of the current version is applied on top of the stored config, keys it doesn't mention are kept.
+// A document of an older version replaces the stored config: keys it doesn't mention get that version's defaults,
+// then the registered migrations bring it up to the current version. Sensitive keys it doesn't mention (exports
+// leave them out unless asked) keep their stored value instead of being reset.
+// Internal keys are skipped, documents exported before a key became internal may still carry it.
+// Managed keys (see `managed.go`) keep the managed file's value.
+func (cfg *Config) Import(ctx context.Context, doc *Document) error {
+	if cfg.profile != "" {
+		return fmt.Errorf("import replaces the base config, run it without --profile / %s", ProfileEnv)
+	}
+	docSchema, ok := cfg.Schemas[doc.Version]
+	if !ok {
+		return fmt.Errorf("unknown schema version '%s'", doc.Version)
+	}
+	if semver.Compare(doc.Version, cfg.Version) > 0 {
+		return fmt.Errorf("document version '%s' is newer than this binary's config version '%s'", doc.Version, cfg.Version)
+	}
+
+	// decode and validate every value against the document's own schema
+	keys := make([]string, 0, len(doc.Values))
+	for key := range doc.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make(map[string]any, len(doc.Values))
+	for _, key := range keys {
+		if key == "version" {
+			return fmt.Errorf("'version' must not be set in values, use the document version")
+		}
+		v, ok := docSchema[key]
+		if !ok {
+			return fmt.Errorf("key '%s' is not part of schema '%s'", key, doc.Version)
+		}
+		if v.IsInternal() {
+			continue
+		}
+		val, err := v.Decode(key, doc.Values[key])
+		if err != nil {
+			return err
+		}
+		if err := v.Validate(val); err != nil {
+			return fmt.Errorf("invalid value for key '%s': %w", key, err)
+		}
+		values[key] = val
+	}
+
+	return cfg.update(changeSource(ctx), func(txn *lmdb.Txn) error {
+		if doc.Version == cfg.Version {
+			for key := range values {
+				if cfg.IsManaged(key) {
+					delete(values, key)
+				}
+			}
+			return cfg.write(txn, values)
+		}
+		// write the document as if it was stored by the older version, then migrate it.
+		// Internal keys are machine specific and sensitive ones are usually left out of exports,
+		// the stored ones are put back afterwards
+		kept, err := cfg.storedRaw(txn, cfg.keptOnImport(doc))
+		if err != nil {
+			return err
+		}
+		if err := txn.Drop(cfg.DBI, false); err != nil {
+			return fmt.Errorf("failed to clear config: %w", err)
+		}
+		for key, v := range docSchema {
+			val, ok := values[key]
+			if !ok {
+				val = v.DefaultValue(ctx)
+			}
+			if key == "version" {
+				val = doc.Version
+			}
+			if err := cfg.put(txn, key, v, val); err != nil {
+				return fmt.Errorf("failed to write key '%s': %w", key, err)
+			}
+		}
+		if err := cfg.migrate(ctx, txn, false); err != nil {
+			return err
+		}
+		for key, data := range kept {
+			if err := txn.Put(cfg.DBI, []byte(key), data, 0); err != nil {
+				return fmt.Errorf("failed to restore key '%s': %w", key, err)
+			}
+		}
+		if err := cfg.putManaged(txn); err != nil {
+			return err
+		}
+		return cfg.checkRulesTxn(txn)
+	})
+}
+
+// keptOnImport returns the keys of the current schema an import of an older doc keeps the stored value of:
+// internal keys except the version, and sensitive keys doc doesn't mention by name or alias.
+func (cfg *Config) keptOnImport(doc *Document) []string {
+	var keys []string
+	for key, v := range cfg.Schemas[cfg.Version] {
+		switch {
+		case key == "version":
+		case v.IsInternal():
+			keys = append(keys, key)
+		case v.IsSensitive():
+			mentioned := false
+			for _, name := range cfg.aliasNames(key) {
+				if _, ok := doc.Values[name]; ok {
+					mentioned = true
+				}
+			}
+			if !mentioned {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// storedRaw returns the stored form of the given keys, keys that aren't stored are left out.
+func (cfg *Config) storedRaw(txn *lmdb.Txn, keys []string) (map[string][]byte, error) {
+	stored := map[string][]byte{}
+	for _, key := range keys {
+		data, err := txn.Get(cfg.DBI, []byte(key))
+		if lmdb.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key '%s': %w", key, err)
+		}
+		stored[key] = bytes.Clone(data) // the drop invalidates it
+	}
+	return stored, nil
+}
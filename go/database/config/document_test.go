@@ -0,0 +1,91 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func TestImportSkipsInternalKeys(t *testing.T) {
+	type v1_0_0 struct {
+		Version string `cfg:"version,internal" default:"v1.0.0"`
+		Port    int    `cfg:"port" default:"8080"`
+		Stamp   string `cfg:"stamp,internal" default:"initial"`
+	}
+	type v1_1_0 struct {
+		Version string `cfg:"version,internal" default:"v1.1.0"`
+		Port    int    `cfg:"port" default:"8080"`
+		Stamp   string `cfg:"stamp,internal" default:"initial"`
+	}
+	schemas := map[string]schema{"v1.0.0": SchemaOf[v1_0_0](), "v1.1.0": SchemaOf[v1_1_0]()}
+	migrations := map[string]MigrationFunc{
+		"v1.0.0->v1.1.0": func(txn *lmdb.Txn, dbi lmdb.DBI, schemas map[string]schema) error { return nil },
+	}
+
+	for _, version := range []string{"v1.1.0", "v1.0.0"} { // current version is merged, older ones replace and migrate
+		t.Run(version, func(t *testing.T) {
+			cfg := testConfig(t, "v1.1.0", schemas, migrations)
+			if err := cfg.DB.Update(func(txn *lmdb.Txn) error {
+				return cfg.put(txn, "stamp", schemas["v1.1.0"]["stamp"], "machine")
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			doc := &Document{Version: version, Values: map[string]json.RawMessage{
+				"port":  json.RawMessage(`9000`),
+				"stamp": json.RawMessage(`"garbage"`),
+			}}
+			if err := cfg.Import(context.Background(), doc); err != nil {
+				t.Fatalf("import failed: %s", err)
+			}
+			if got := lookup(t, cfg, "stamp"); got != "machine" {
+				t.Errorf("internal key 'stamp' is %v after import, want it unchanged", got)
+			}
+			if got := lookup(t, cfg, "port"); got != 9000 {
+				t.Errorf("'port' is %v after import, want 9000", got)
+			}
+		})
+	}
+}
+
+func TestImportKeepsSensitiveKeys(t *testing.T) {
+	type v1_0_0 struct {
+		Version string `cfg:"version,internal" default:"v1.0.0"`
+		Port    int    `cfg:"port" default:"8080"`
+		Token   string `cfg:"token,sensitive"`
+		Secret  string `cfg:"secret,sensitive"`
+	}
+	type v1_1_0 struct {
+		Version string `cfg:"version,internal" default:"v1.1.0"`
+		Port    int    `cfg:"port" default:"8080"`
+		Token   string `cfg:"token,sensitive"`
+		Secret  string `cfg:"secret,sensitive"`
+	}
+	schemas := map[string]schema{"v1.0.0": SchemaOf[v1_0_0](), "v1.1.0": SchemaOf[v1_1_0]()}
+	migrations := map[string]MigrationFunc{
+		"v1.0.0->v1.1.0": func(txn *lmdb.Txn, dbi lmdb.DBI, schemas map[string]schema) error { return nil },
+	}
+	cfg := testConfig(t, "v1.1.0", schemas, migrations)
+	ctx := IntoContext(cfg.ctx, cfg)
+	for key, val := range map[string]string{"token": "stored-token", "secret": "stored-secret"} {
+		if err := Set(ctx, key, val); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// an export without sensitive values, plus one the user chose to include
+	doc := &Document{Version: "v1.0.0", Values: map[string]json.RawMessage{
+		"port":   json.RawMessage(`9000`),
+		"secret": json.RawMessage(`"imported-secret"`),
+	}}
+	if err := cfg.Import(ctx, doc); err != nil {
+		t.Fatalf("import failed: %s", err)
+	}
+	for key, want := range map[string]any{"port": 9000, "token": "stored-token", "secret": "imported-secret"} {
+		if got := lookup(t, cfg, key); got != want {
+			t.Errorf("'%s' is %v after import, want %v", key, got, want)
+		}
+	}
+}
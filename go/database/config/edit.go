@@ -0,0 +1,309 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// `goweb config edit` renders the config as JSON with a comment block per key (see [Config.EditDocument]),
+// lets the user change it in $EDITOR and applies it with [Config.ApplyEdit]. On errors the edited text is
+// handed back with an "// ERROR:" line above each offending key (see [AnnotateEdit]) and the editor reopens.
+//
+// Comments have to be on their own line, they're blanked before parsing so error line numbers still match.
+// Only keys whose value differs from the rendered one are written, so edits made by other processes in the
+// meantime to other keys are kept, and a conflicting edit of the same key is refused.
+
+const editErrorPrefix = "// ERROR: "
+
+// EditError is a problem with an edited document, tied to a key or a line where possible.
+type EditError struct {
+	Key  string // empty if the problem isn't about a single key, e.g. a cross-key rule
+	Line int    // 1-based, 0 if unknown, only set for syntax errors
+	Err  error
+}
+
+func (e EditError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("'%s': %s", e.Key, e.Err)
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+	return e.Err.Error()
+}
+
+// EditErrors is returned by [Config.ApplyEdit] when the edited document is invalid, nothing was written.
+type EditErrors []EditError
+
+func (e EditErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "invalid config edit: " + strings.Join(msgs, "; ")
+}
+
+// EditDocument renders every non-internal key as commented JSON for `config edit`.
+// With a profile active, values are as seen through it and changes go to it. Sensitive values are shown as [Redacted].
+func (cfg *Config) EditDocument() ([]byte, error) {
+	var b bytes.Buffer
+	target := "the base config"
+	if cfg.profile != "" {
+		target = fmt.Sprintf("profile '%s'", cfg.profile)
+	}
+	fmt.Fprintf(&b, "// Editing %s, schema %s. Save and close the editor to apply every change in one transaction.\n", target, cfg.Version)
+	fmt.Fprintf(&b, "// Lines starting with // are ignored. Removing a key leaves it unchanged, use `config reset` for that.\n")
+	fmt.Fprintf(&b, "// Closing without changes, or with an empty file, cancels the edit.\n")
+	b.WriteString("{\n")
+	err := cfg.DB.View(func(txn *lmdb.Txn) error {
+		var keys []string
+		for _, key := range cfg.Keys() {
+			if !cfg.Schemas[cfg.Version][key].IsInternal() {
+				keys = append(keys, key)
+			}
+		}
+		for i, key := range keys {
+			v := cfg.Schemas[cfg.Version][key]
+			val, _, err := cfg.stored(txn, key, v)
+			if err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(cfg.Redact(key, val), "  ", "  ")
+			if err != nil {
+				return fmt.Errorf("marshal error for key '%s': %w", key, err)
+			}
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			for _, line := range cfg.editComment(key) {
+				fmt.Fprintf(&b, "  // %s\n", line)
+			}
+			fmt.Fprintf(&b, "  %q: %s", key, data)
+			if i < len(keys)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
+
+// editComment returns the comment lines rendered above a key.
+func (cfg *Config) editComment(key string) []string {
+	info, err := cfg.Describe(key)
+	if err != nil {
+		return nil
+	}
+	var lines []string
+	if info.Description != "" {
+		lines = append(lines, info.Description)
+	}
+	def := Format(info.Default)
+	if def == "" {
+		def = `""`
+	}
+	if info.DefaultDoc != "" {
+		def = info.DefaultDoc
+	}
+	lines = append(lines, fmt.Sprintf("type: %s, default: %s", info.Type, def))
+	if notes := info.Notes(); len(notes) > 0 {
+		lines = append(lines, strings.Join(notes, "; "))
+	}
+	if info.Sensitive {
+		lines = append(lines, fmt.Sprintf("leave %q to keep the current value", Redacted))
+	}
+	if info.Managed {
+		lines = append(lines, fmt.Sprintf("managed by %s, read-only here", cfg.managed.path))
+	}
+	if _, ok := os.LookupEnv(info.EnvName); ok && !info.Internal {
+		lines = append(lines, fmt.Sprintf("overridden by %s, editing changes the stored value only", info.EnvName))
+	}
+	return lines
+}
+
+// ApplyEdit writes the keys whose value differs between original (as returned by [Config.EditDocument])
+// and edited in one transaction and returns them sorted. Values are checked against the current schema's types,
+// validators and rules first, problems are returned as [EditErrors] and nothing is written.
+func (cfg *Config) ApplyEdit(ctx context.Context, original, edited []byte) ([]string, error) {
+	before, errs := cfg.parseEdit(original, false) // stored values may be invalid already, that's no reason to refuse a fix
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to parse the original document: %w", errs)
+	}
+	after, errs := cfg.parseEdit(edited, true)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	updates := map[string]any{}
+	for key, val := range after {
+		if old, ok := before[key]; !ok || !reflect.DeepEqual(old, val) {
+			if cfg.IsManaged(key) {
+				errs = append(errs, EditError{Key: key, Err: fmt.Errorf("managed by %s, change it there", cfg.managed.path)})
+				continue
+			}
+			updates[key] = val
+		}
+	}
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+		return nil, errs
+	}
+	changed := make([]string, 0, len(updates))
+	for key := range updates {
+		changed = append(changed, key)
+	}
+	sort.Strings(changed)
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	err := cfg.update(changeSource(ctx), func(txn *lmdb.Txn) error {
+		for _, key := range changed {
+			cur, _, err := cfg.stored(txn, key, cfg.Schemas[cfg.Version][key])
+			if err != nil {
+				return err
+			}
+			if old, ok := before[key]; ok && !reflect.DeepEqual(old, cur) {
+				return EditErrors{{Key: key, Err: fmt.Errorf("changed by someone else while editing, it's now %s. Give up and edit again to start from the current values", Format(cfg.Redact(key, cur)))}}
+			}
+			// validators may see a different world than when the edit was parsed, e.g. a file that's gone since
+			if err := cfg.Schemas[cfg.Version][key].Validate(updates[key]); err != nil {
+				return EditErrors{{Key: key, Err: err}}
+			}
+			if err := cfg.set(txn, key, updates[key]); err != nil {
+				return err
+			}
+		}
+		if err := cfg.checkRulesTxn(txn); err != nil {
+			return EditErrors{{Err: err}}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// parseEdit decodes an edit document, checking each value's type and, if validate is set, validators.
+// Sensitive keys left as [Redacted] are skipped.
+func (cfg *Config) parseEdit(doc []byte, validate bool) (map[string]any, EditErrors) {
+	stripped := stripComments(doc)
+	if len(bytes.TrimSpace(stripped)) == 0 {
+		return nil, EditErrors{{Err: errors.New("document is empty")}}
+	}
+	var raw map[string]json.RawMessage
+	dec := json.NewDecoder(bytes.NewReader(stripped))
+	if err := dec.Decode(&raw); err != nil {
+		e := EditError{Err: err}
+		var syntax *json.SyntaxError
+		if errors.As(err, &syntax) {
+			e.Line = 1 + bytes.Count(stripped[:syntax.Offset], []byte("\n"))
+		}
+		return nil, EditErrors{e}
+	}
+	if dec.More() {
+		return nil, EditErrors{{Err: errors.New("unexpected data after the closing }")}}
+	}
+
+	values := make(map[string]any, len(raw))
+	var errs EditErrors
+	for key, data := range raw {
+		v, ok := cfg.Schemas[cfg.Version][key]
+		switch {
+		case !ok:
+			errs = append(errs, EditError{Key: key, Err: errors.New("unknown key")})
+			continue
+		case v.IsInternal():
+			errs = append(errs, EditError{Key: key, Err: errors.New("key is internal and can't be edited")})
+			continue
+		case v.IsSensitive() && string(bytes.TrimSpace(data)) == fmt.Sprintf("%q", Redacted):
+			continue
+		}
+		val, err := decodeAs(v.Type(), data)
+		if err == nil && validate {
+			err = v.Validate(val.Interface())
+		}
+		if err != nil {
+			errs = append(errs, EditError{Key: key, Err: err})
+			continue
+		}
+		values[key] = val.Interface()
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+	return values, errs
+}
+
+// stripComments blanks every line starting with //, keeping the line count.
+func stripComments(doc []byte) []byte {
+	lines := bytes.Split(doc, []byte("\n"))
+	for i, line := range lines {
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte("//")) {
+			lines[i] = nil
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// AnnotateEdit returns doc with the errors of a previous attempt replaced by errs, each as an "// ERROR:"
+// line above the key or line it's about. Errors that aren't tied to either go on top.
+func AnnotateEdit(doc []byte, errs EditErrors) []byte {
+	lines := strings.Split(string(doc), "\n")
+	above := map[int][]string{} // line index -> errors
+	for _, e := range errs {
+		i := -1
+		switch {
+		case e.Line > 0 && e.Line <= len(lines):
+			i = e.Line - 1
+		case e.Key != "":
+			i = findKeyLine(lines, e.Key)
+		}
+		above[i] = append(above[i], e.Error())
+	}
+
+	var b strings.Builder
+	for _, msg := range above[-1] {
+		b.WriteString(editErrorPrefix + msg + "\n")
+	}
+	for i, line := range lines {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		for _, msg := range above[i] {
+			b.WriteString(indent + editErrorPrefix + msg + "\n")
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), editErrorPrefix) {
+			continue
+		}
+		b.WriteString(line)
+		if i < len(lines)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return []byte(b.String())
+}
+
+// findKeyLine returns the index of the line defining key, preferring lines starting with it, -1 if there's none.
+func findKeyLine(lines []string, key string) int {
+	quoted := regexp.QuoteMeta(fmt.Sprintf("%q", key)) + `\s*:`
+	for _, pattern := range []*regexp.Regexp{regexp.MustCompile(`^\s*` + quoted), regexp.MustCompile(quoted)} {
+		for i, line := range lines {
+			if !strings.HasPrefix(strings.TrimSpace(line), "//") && pattern.MatchString(line) {
+				return i
+			}
+		}
+	}
+	return -1
+}
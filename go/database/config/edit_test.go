@@ -0,0 +1,56 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestApplyEditErrors(t *testing.T) {
+	type v1_0_0 struct {
+		Version string `cfg:"version,internal" default:"v1.0.0"`
+		Port    int    `cfg:"port" default:"8080"`
+		Dir     string `cfg:"dir" default:"/data"`
+	}
+	calls := 0
+	schemas := map[string]schema{"v1.0.0": SchemaOf[v1_0_0](
+		Checks("dir", func(string) error { // passes while parsing, fails when applied, like a file removed meanwhile
+			if calls++; calls%2 == 0 {
+				return errors.New("gone")
+			}
+			return nil
+		}),
+	)}
+	cfg := testConfig(t, "v1.0.0", schemas, nil)
+	cfg.Rules = map[string][]Rule{"v1.0.0": {func(values map[string]any) error {
+		if values["port"].(int) < 1024 {
+			return fmt.Errorf("port must be at least 1024")
+		}
+		return nil
+	}}}
+	original, err := cfg.EditDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name, from, to, key string
+	}{
+		{name: "validator", from: `"dir": "/data"`, to: `"dir": "/srv"`, key: "dir"},
+		{name: "rule", from: `"port": 8080`, to: `"port": 80`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			edited := strings.Replace(string(original), tt.from, tt.to, 1)
+			_, err := cfg.ApplyEdit(cfg.ctx, original, []byte(edited))
+			var errs EditErrors
+			if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Key != tt.key {
+				t.Fatalf("got %v, want EditErrors for key '%s'", err, tt.key)
+			}
+		})
+	}
+	if got := lookup(t, cfg, "dir"); got != "/data" {
+		t.Fatalf("dir is %v after failed edits, want it unchanged", got)
+	}
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// Template variables ---------------------------------------------------------
+
+// EnvPrefix is prepended to a key's upper snake case name to get the environment variable
+// that overrides it, e.g. "logLevel" -> "GOWEB_LOG_LEVEL". The systemd unit loads `~/.goweb/goweb.env`.
+const EnvPrefix = "GOWEB_"
+
+// ----------------------------------------------------------------------------
+
+// Source is the layer a resolved config value came from.
+type Source string
+
+const (
+	SourceDefault Source = "default" // key is not stored, schema default is used
+	SourceDB      Source = "db"      // value stored in the config DBI
+	SourceProfile Source = "profile" // value stored in the active profile, see `profile.go`
+	SourceEnv     Source = "env"     // overridden by an environment variable
+)
+
+// EnvName returns the environment variable that overrides the given key.
+// Acronyms are kept together, e.g. "useTLS" -> "GOWEB_USE_TLS".
+func EnvName(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextIsLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// resolve returns the effective value of a key, checking the env, profile, db, and default layers in that order.
+// Internal keys can't be overridden, their env var is ignored. Overrides go through the key's validators
+// like stored values, an invalid one is an error rather than handed to the caller.
+func (cfg *Config) resolve(txn *lmdb.Txn, key string, v *value) (any, Source, error) {
+	if raw, ok := os.LookupEnv(EnvName(key)); ok && !v.IsInternal() {
+		val, err := v.Parse(raw)
+		if err == nil {
+			err = v.Validate(val)
+		}
+		if err != nil {
+			return nil, SourceEnv, fmt.Errorf("invalid value in %s: %w", EnvName(key), err)
+		}
+		return val, SourceEnv, nil
+	}
+	return cfg.stored(txn, key, v)
+}
+
+// stored returns the value of a key ignoring env overrides, falling back to the schema default if it isn't stored.
+func (cfg *Config) stored(txn *lmdb.Txn, key string, v *value) (any, Source, error) {
+	return cfg.storedAs(txn, cfg.profile, key, v)
+}
+
+// storedAs is like stored but as seen by the given profile, "" being the base config.
+func (cfg *Config) storedAs(txn *lmdb.Txn, profile string, key string, v *value) (any, Source, error) {
+	if profile != "" && !v.IsInternal() {
+		data, err := txn.Get(cfg.ProfileDBI, profileKey(profile, key))
+		if err == nil {
+			val, err := cfg.decode(key, v, data)
+			return val, SourceProfile, err
+		}
+		if !lmdb.IsNotFound(err) {
+			return nil, SourceProfile, fmt.Errorf("failed to read key '%s' of profile '%s': %w", key, profile, err)
+		}
+	}
+	data, err := txn.Get(cfg.DBI, []byte(key))
+	if err != nil {
+		if lmdb.IsNotFound(err) {
+			return v.DefaultValue(cfg.context()), SourceDefault, nil
+		}
+		return nil, SourceDB, fmt.Errorf("failed to read config key '%s': %w", key, err)
+	}
+	val, err := cfg.decode(key, v, data)
+	if err != nil {
+		return nil, SourceDB, err
+	}
+	return val, SourceDB, nil
+}
+
+// storedValues returns the stored (or default) value of every key in the current schema.
+func (cfg *Config) storedValues(txn *lmdb.Txn) (map[string]any, error) {
+	return cfg.storedValuesAs(txn, cfg.profile)
+}
+
+// storedValuesAs is like storedValues but as seen by the given profile, "" being the base config.
+func (cfg *Config) storedValuesAs(txn *lmdb.Txn, profile string) (map[string]any, error) {
+	values := make(map[string]any, len(cfg.Schemas[cfg.Version]))
+	for key, v := range cfg.Schemas[cfg.Version] {
+		val, _, err := cfg.storedAs(txn, profile, key, v)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = val
+	}
+	return values, nil
+}
+
+// effectiveValues is like storedValues but with env overrides applied.
+func (cfg *Config) effectiveValues(txn *lmdb.Txn) (map[string]any, error) {
+	values := make(map[string]any, len(cfg.Schemas[cfg.Version]))
+	for key, v := range cfg.Schemas[cfg.Version] {
+		val, _, err := cfg.resolve(txn, key, v)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = val
+	}
+	return values, nil
+}
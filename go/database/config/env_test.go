@@ -0,0 +1,19 @@
+package config
+
+import "testing"
+
+func TestEnvOverrideIsValidated(t *testing.T) {
+	cfg := testConfig(t, Version, SchemaRecord, Migrations)
+	ctx := IntoContext(cfg.ctx, cfg)
+
+	t.Setenv(EnvName("port"), "9000")
+	if port, err := Get[int](ctx, "port"); err != nil || port != 9000 {
+		t.Errorf("Get(port) = %d, %v with a valid override, want 9000", port, err)
+	}
+	for _, raw := range []string{"-5", "70000", "abc"} {
+		t.Setenv(EnvName("port"), raw)
+		if port, err := Get[int](ctx, "port"); err == nil {
+			t.Errorf("Get(port) = %d with %s=%s, want an error", port, EnvName("port"), raw)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os/user"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// Template variables ---------------------------------------------------------
+
+var (
+	HistoryLimit  = 1000                 // max entries kept, oldest are dropped first
+	HistoryMaxAge = 180 * 24 * time.Hour // entries older than this are dropped
+)
+
+// ----------------------------------------------------------------------------
+
+// MigrationChangeSource is the history source of changes made by [Config.Migrate].
+const MigrationChangeSource = "migration"
+
+// HistoryEntry records a single key change in the config history DBI, keyed by ID (big endian).
+// Old and New hold the stored form of the value, so sensitive values stay encrypted.
+// Either is null if the key wasn't stored, i.e. it was (or became) the schema default.
+type HistoryEntry struct {
+	ID      uint64          `json:"-"`
+	Key     string          `json:"key"`
+	Profile string          `json:"profile,omitempty"` // empty for the base config
+	Old     json.RawMessage `json:"old"`
+	New     json.RawMessage `json:"new"`
+	Time    time.Time       `json:"time"`
+	Source  string          `json:"source"`  // who made the change, see ChangeSourceIntoContext
+	Version string          `json:"version"` // binary version that made the change
+}
+
+type changeSourceKey struct{}
+
+// ChangeSourceIntoContext sets the source recorded in the history for writes made with ctx, e.g. "http 10.0.0.5:51234".
+// Without it, writes are recorded as "cli:<os user>".
+func ChangeSourceIntoContext(ctx context.Context, src string) context.Context {
+	return context.WithValue(ctx, changeSourceKey{}, src)
+}
+
+func changeSource(ctx context.Context) string {
+	if src, ok := ctx.Value(changeSourceKey{}).(string); ok {
+		return src
+	}
+	if u, err := user.Current(); err == nil {
+		return "cli:" + u.Username
+	}
+	return "cli"
+}
+
+// update runs fn in a write transaction and records every key it changed in the history.
+// All config writes go through here, it also bumps the cache generation if a value changed, see `cache.go`.
+func (cfg *Config) update(src string, fn func(txn *lmdb.Txn) error) error {
+	return cfg.updateSteps(changeStep{src, fn})
+}
+
+// changeStep is part of a write transaction whose changes are recorded with their own source.
+type changeStep struct {
+	src string
+	fn  func(txn *lmdb.Txn) error
+}
+
+// updateSteps is update for a transaction made of several steps, e.g. a migration followed by applying the managed file.
+func (cfg *Config) updateSteps(steps ...changeStep) error {
+	return cfg.DB.Update(func(txn *lmdb.Txn) error {
+		changed := false
+		for _, step := range steps {
+			before, err := cfg.rawValues(txn)
+			if err != nil {
+				return err
+			}
+			if err := step.fn(txn); err != nil {
+				return err
+			}
+			stepChanged, err := cfg.record(txn, step.src, before)
+			if err != nil {
+				return err
+			}
+			changed = changed || stepChanged
+		}
+		if !changed {
+			return nil // e.g. Migrate on every start, keep the caches and watchers of running processes quiet
+		}
+		return cfg.bumpGeneration(txn)
+	})
+}
+
+// rawValues returns every stored key and its stored form, including keys unknown to the current schema.
+// Profile overrides are included as "profile/key".
+func (cfg *Config) rawValues(txn *lmdb.Txn) (map[string][]byte, error) {
+	values := map[string][]byte{}
+	if err := cfg.eachEntry(txn, func(key string, data []byte) error {
+		values[key] = data
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	err := cfg.eachProfileEntry(txn, "", func(profile, key string, data []byte) error {
+		if key != "" {
+			values[string(profileKey(profile, key))] = data
+		}
+		return nil
+	})
+	return values, err
+}
+
+// record appends an entry for every key that differs between before and the config stored in txn, then prunes old entries.
+// Internal keys aren't recorded, except version so migrations show up. Reports whether any value changed, internal ones included.
+func (cfg *Config) record(txn *lmdb.Txn, src string, before map[string][]byte) (bool, error) {
+	after, err := cfg.rawValues(txn)
+	if err != nil {
+		return false, err
+	}
+	keys := make([]string, 0, len(after))
+	for key := range after {
+		keys = append(keys, key)
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	now := time.Now().UTC()
+	changed := false
+	for _, k := range keys {
+		old, new := before[k], after[k]
+		profile, key, ok := strings.Cut(k, "/")
+		if !ok {
+			profile, key = "", k
+		}
+		if !cfg.changed(key, old, new) {
+			continue
+		}
+		changed = true
+		if v, ok := cfg.Schemas[cfg.Version][key]; ok && v.IsInternal() && key != "version" {
+			continue
+		}
+		entry := HistoryEntry{Key: key, Profile: profile, Old: old, New: new, Time: now, Source: src, Version: cfg.binVersion}
+		if err := cfg.appendHistory(txn, &entry); err != nil {
+			return false, err
+		}
+	}
+	return changed, cfg.pruneHistory(txn)
+}
+
+// changed reports whether a key's stored form changed. Sensitive values are re-encrypted with a fresh nonce
+// on every write, so values of known keys are compared decoded.
+func (cfg *Config) changed(key string, old, new []byte) bool {
+	if bytes.Equal(old, new) {
+		return false
+	}
+	v, ok := cfg.Schemas[cfg.Version][key]
+	if !ok || old == nil || new == nil {
+		return true
+	}
+	oldVal, err := cfg.decode(key, v, old)
+	if err != nil {
+		return true
+	}
+	newVal, err := cfg.decode(key, v, new)
+	if err != nil {
+		return true
+	}
+	return !reflect.DeepEqual(oldVal, newVal)
+}
+
+func (cfg *Config) appendHistory(txn *lmdb.Txn, entry *HistoryEntry) error {
+	cur, err := txn.OpenCursor(cfg.HistoryDBI)
+	if err != nil {
+		return fmt.Errorf("failed to open history cursor: %w", err)
+	}
+	defer cur.Close()
+	entry.ID = 1
+	k, _, err := cur.Get(nil, nil, lmdb.Last)
+	if err == nil {
+		entry.ID = binary.BigEndian.Uint64(k) + 1
+	} else if !lmdb.IsNotFound(err) {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := txn.Put(cfg.HistoryDBI, historyKey(entry.ID), data, 0); err != nil {
+		return fmt.Errorf("failed to write history entry: %w", err)
+	}
+	return nil
+}
+
+// pruneHistory drops the oldest entries beyond HistoryLimit or HistoryMaxAge.
+func (cfg *Config) pruneHistory(txn *lmdb.Txn) error {
+	stat, err := txn.Stat(cfg.HistoryDBI)
+	if err != nil {
+		return fmt.Errorf("failed to stat history: %w", err)
+	}
+	count := stat.Entries
+	cur, err := txn.OpenCursor(cfg.HistoryDBI)
+	if err != nil {
+		return fmt.Errorf("failed to open history cursor: %w", err)
+	}
+	defer cur.Close()
+	cutoff := time.Now().Add(-HistoryMaxAge)
+	for count > 0 {
+		_, v, err := cur.Get(nil, nil, lmdb.First)
+		if lmdb.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read history: %w", err)
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(v, &entry); err == nil && count <= uint64(HistoryLimit) && entry.Time.After(cutoff) {
+			return nil
+		}
+		if err := cur.Del(0); err != nil {
+			return fmt.Errorf("failed to prune history: %w", err)
+		}
+		count--
+	}
+	return nil
+}
+
+// History returns up to limit entries, newest first. Only entries of key are returned if it's not empty.
+// A limit <= 0 returns every entry.
+func (cfg *Config) History(key string, limit int) ([]HistoryEntry, error) {
+	var names []string // the key and its old names, entries keep the name they were recorded under
+	if key != "" {
+		canonical, err := cfg.canonical(key)
+		if err != nil {
+			return nil, err
+		}
+		names = cfg.aliasNames(canonical)
+	}
+	var entries []HistoryEntry
+	err := cfg.DB.View(func(txn *lmdb.Txn) error {
+		cur, err := txn.OpenCursor(cfg.HistoryDBI)
+		if err != nil {
+			return fmt.Errorf("failed to open history cursor: %w", err)
+		}
+		defer cur.Close()
+		for op := uint(lmdb.Last); limit <= 0 || len(entries) < limit; op = lmdb.Prev {
+			k, v, err := cur.Get(nil, nil, op)
+			if lmdb.IsNotFound(err) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read history: %w", err)
+			}
+			var entry HistoryEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return fmt.Errorf("corrupt history entry: %w", err)
+			}
+			entry.ID = binary.BigEndian.Uint64(k)
+			if key == "" || slices.Contains(names, entry.Key) {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Revert sets the key of a history entry back to the entry's old value, validated against the current schema.
+// If the key wasn't stored before the change, it's removed so the schema default (or base value, in a profile) applies again.
+func (cfg *Config) Revert(ctx context.Context, id uint64) (*HistoryEntry, error) {
+	var entry HistoryEntry
+	src := fmt.Sprintf("%s (revert of #%d)", changeSource(ctx), id)
+	err := cfg.update(src, func(txn *lmdb.Txn) error {
+		data, err := txn.Get(cfg.HistoryDBI, historyKey(id))
+		if err != nil {
+			if lmdb.IsNotFound(err) {
+				return fmt.Errorf("history entry #%d not found", id)
+			}
+			return fmt.Errorf("failed to read history entry #%d: %w", id, err)
+		}
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return fmt.Errorf("corrupt history entry #%d: %w", id, err)
+		}
+		entry.ID = id
+		key, err := cfg.canonical(entry.Key) // the entry may predate a rename
+		if err != nil {
+			return fmt.Errorf("cannot revert #%d: %w", id, err)
+		}
+		entry.Key = key
+		v, err := cfg.lookup(entry.Key)
+		if err != nil {
+			return fmt.Errorf("cannot revert #%d: %w", id, err)
+		}
+		if v.IsInternal() {
+			return fmt.Errorf("cannot revert #%d: key '%s' is internal", id, entry.Key)
+		}
+		if err := cfg.checkManaged(entry.Key); err != nil {
+			return fmt.Errorf("cannot revert #%d: %w", id, err)
+		}
+		// the entry may belong to another profile than the active one
+		k := []byte(entry.Key)
+		dbi := cfg.DBI
+		if entry.Profile != "" {
+			if err := cfg.profileExists(txn, entry.Profile); err != nil {
+				return fmt.Errorf("cannot revert #%d: %w", id, err)
+			}
+			k, dbi = profileKey(entry.Profile, entry.Key), cfg.ProfileDBI
+		}
+		if unset(entry.Old) {
+			if err := txn.Del(dbi, k, nil); err != nil && !lmdb.IsNotFound(err) {
+				return fmt.Errorf("failed to delete key '%s': %w", entry.Key, err)
+			}
+		} else {
+			val, err := cfg.decode(entry.Key, v, entry.Old)
+			if err == nil {
+				err = v.Validate(val)
+			}
+			if err != nil {
+				return fmt.Errorf("cannot revert #%d, old value doesn't fit the current schema: %w", id, err)
+			}
+			if err := cfg.putAs(txn, entry.Profile, entry.Key, v, val); err != nil {
+				return fmt.Errorf("failed to write key '%s': %w", entry.Key, err)
+			}
+		}
+		values, err := cfg.storedValuesAs(txn, entry.Profile)
+		if err != nil {
+			return err
+		}
+		return cfg.checkRules(values)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// HistoryValue renders the stored form of a value from a history entry for display, redacting sensitive keys.
+func (cfg *Config) HistoryValue(key string, raw json.RawMessage) string {
+	if unset(raw) {
+		return "(unset)"
+	}
+	v, ok := cfg.Schemas[cfg.Version][key]
+	if !ok {
+		return string(raw) // key isn't part of the current schema anymore
+	}
+	val, err := cfg.decode(key, v, raw)
+	if err != nil {
+		return string(raw)
+	}
+	if s := Format(cfg.Redact(key, val)); s != "" {
+		return s
+	}
+	return `""`
+}
+
+func historyKey(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, id)
+}
+
+// unset reports whether a history value means "not stored". Nil marshals to null.
+func unset(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
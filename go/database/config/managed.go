@@ -0,0 +1,213 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+	"github.com/Data-Corruption/stdx/xlog"
+)
+
+// Machines can be provisioned by dropping a ManagedFileName file into the data directory, a JSON object of
+// key -> value in the current schema, e.g. {"port": 9000, "cors": {"origins": ["https://x"]}}. The keys it lists
+// are managed: [Config.Migrate] (so every start) writes their values into the base config, in the same txn,
+// and `config set|reset|edit|import|revert` refuse to change them. LMDB stays the source of truth at runtime,
+// the file only decides what gets written there. Only JSON is supported.
+//
+// The values last applied are kept in the config meta DBI. If a managed key's stored value differs from them
+// at the next start, something changed it behind the file's back (e.g. the app itself or an older binary),
+// that drift is logged as a warning before the file's value is written again. Keys removed from the file
+// keep their value and become writable again.
+
+// Template variables ---------------------------------------------------------
+
+// ManagedFileName is the name of the declarative config file in the data directory.
+var ManagedFileName = "goweb.json"
+
+// ----------------------------------------------------------------------------
+
+// ManagedChangeSource is the history source of changes made by applying the managed file.
+const ManagedChangeSource = "managed file"
+
+var managedKey = []byte("managed") // meta DBI: key -> stored form of the value last applied from the managed file
+
+// managedFile is the loaded managed file, values holds the decoded value of every key it sets.
+type managedFile struct {
+	path   string // empty if there is none
+	values map[string]any
+}
+
+// loadManaged reads the managed file at path, decoding and validating every value. A missing file manages nothing.
+func (cfg *Config) loadManaged(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		cfg.managed = managedFile{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	obj, err := decodeAs(reflect.TypeFor[map[string]json.RawMessage](), data)
+	if err != nil {
+		return fmt.Errorf("%s is not a JSON object: %w", path, err)
+	}
+	raw := obj.Interface().(map[string]json.RawMessage)
+	managed := make(map[string]any, len(raw))
+	for name, data := range raw {
+		key, err := cfg.canonical(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if _, dup := managed[key]; dup {
+			return fmt.Errorf("%s: key '%s' is set more than once, under an old name too", path, key)
+		}
+		v, ok := cfg.Schemas[cfg.Version][key]
+		switch {
+		case !ok:
+			return fmt.Errorf("%s: key '%s' is not part of schema '%s'", path, key, cfg.Version)
+		case v.IsInternal():
+			return fmt.Errorf("%s: key '%s' is internal and can't be managed", path, key)
+		}
+		val, err := decodeAs(v.Type(), data)
+		if err == nil {
+			err = v.Validate(val.Interface())
+		}
+		if err != nil {
+			return fmt.Errorf("%s: invalid value for key '%s': %w", path, key, err)
+		}
+		managed[key] = val.Interface()
+	}
+	cfg.managed = managedFile{path: path, values: managed}
+	return nil
+}
+
+// IsManaged reports whether key is set by the managed file, paths count as their key.
+func (cfg *Config) IsManaged(key string) bool {
+	_, ok := cfg.managed.values[PathKey(key)]
+	return ok
+}
+
+// ManagedPath returns the path of the managed file, empty if there is none.
+func (cfg *Config) ManagedPath() string { return cfg.managed.path }
+
+// checkManaged returns an error if key can't be changed by hand because the managed file sets it.
+func (cfg *Config) checkManaged(key string) error {
+	if cfg.IsManaged(key) {
+		return fmt.Errorf("key '%s' is managed by %s, change it there", PathKey(key), cfg.managed.path)
+	}
+	return nil
+}
+
+// reconcileManaged writes the managed values into the base config, reporting drift, and drops profile overrides of managed keys.
+func (cfg *Config) reconcileManaged(ctx context.Context, txn *lmdb.Txn) error {
+	last := map[string]json.RawMessage{}
+	data, err := txn.Get(cfg.MetaDBI, managedKey)
+	switch {
+	case err == nil:
+		data = bytes.Clone(data) // compared after writing
+		if err := json.Unmarshal(data, &last); err != nil {
+			return fmt.Errorf("corrupt managed config record: %w", err)
+		}
+	case !lmdb.IsNotFound(err):
+		return fmt.Errorf("failed to read managed config record: %w", err)
+	}
+
+	keys := make([]string, 0, len(cfg.managed.values))
+	for key := range cfg.managed.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	applied := make(map[string]json.RawMessage, len(keys))
+	for _, key := range keys {
+		v, want := cfg.Schemas[cfg.Version][key], cfg.managed.values[key]
+		cur, _, curErr := cfg.storedAs(txn, "", key, v)
+		if prev, ok := last[key]; ok && curErr == nil {
+			// the file may have changed since, drift is the stored value moving away from what was applied last
+			if old, err := cfg.decode(key, v, prev); err == nil && !reflect.DeepEqual(old, cur) && !reflect.DeepEqual(cur, want) {
+				msg := fmt.Sprintf("config key '%s' was changed to %s outside %s, resetting it to %s", key,
+					Format(cfg.Redact(key, cur)), ManagedFileName, Format(cfg.Redact(key, want)))
+				xlog.Warn(ctx, msg)
+				cfg.printf("warning: %s\n", msg)
+			}
+		}
+		if curErr != nil || !reflect.DeepEqual(cur, want) {
+			if err := cfg.putAs(txn, "", key, v, want); err != nil {
+				return fmt.Errorf("failed to write managed key '%s': %w", key, err)
+			}
+			cfg.printf("config file: '%s' set to %s\n", key, Format(cfg.Redact(key, want)))
+		}
+		if prev, ok := last[key]; ok {
+			if old, err := cfg.decode(key, v, prev); err == nil && reflect.DeepEqual(old, want) {
+				applied[key] = prev // unchanged, keep it so sensitive values aren't re-encrypted on every start
+				continue
+			}
+		}
+		if applied[key], err = cfg.encode(key, v, want); err != nil {
+			return err
+		}
+	}
+	for key := range last {
+		if _, ok := cfg.managed.values[key]; !ok {
+			cfg.printf("config file: '%s' is no longer managed\n", key)
+		}
+	}
+
+	// overrides would hide the managed value in their profile
+	var overrides [][]byte
+	if err := cfg.eachProfileEntry(txn, "", func(profile, key string, _ []byte) error {
+		if _, ok := cfg.managed.values[key]; ok {
+			cfg.printf("config file: dropping '%s' from profile '%s', it's managed\n", key, profile)
+			overrides = append(overrides, profileKey(profile, key))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, k := range overrides {
+		if err := txn.Del(cfg.ProfileDBI, k, nil); err != nil {
+			return fmt.Errorf("failed to drop managed profile override: %w", err)
+		}
+	}
+
+	if err := cfg.checkRulesTxn(txn); err != nil {
+		return fmt.Errorf("config with %s applied is invalid: %w", ManagedFileName, err)
+	}
+	return cfg.putManagedRecord(txn, data, applied)
+}
+
+// putManagedRecord stores applied as the last applied managed values, unless it's unchanged from old.
+func (cfg *Config) putManagedRecord(txn *lmdb.Txn, old []byte, applied map[string]json.RawMessage) error {
+	if len(applied) == 0 {
+		if err := txn.Del(cfg.MetaDBI, managedKey, nil); err != nil && !lmdb.IsNotFound(err) {
+			return fmt.Errorf("failed to delete managed config record: %w", err)
+		}
+		return nil
+	}
+	data, err := json.Marshal(applied)
+	if err != nil {
+		return fmt.Errorf("failed to marshal managed config record: %w", err)
+	}
+	if bytes.Equal(data, old) {
+		return nil
+	}
+	if err := txn.Put(cfg.MetaDBI, managedKey, data, 0); err != nil {
+		return fmt.Errorf("failed to write managed config record: %w", err)
+	}
+	return nil
+}
+
+// putManaged writes the managed values into the base config without reporting anything, e.g. after an import replaced it.
+func (cfg *Config) putManaged(txn *lmdb.Txn) error {
+	for key, val := range cfg.managed.values {
+		if err := cfg.putAs(txn, "", key, cfg.Schemas[cfg.Version][key], val); err != nil {
+			return fmt.Errorf("failed to write managed key '%s': %w", key, err)
+		}
+	}
+	return nil
+}
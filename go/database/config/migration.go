@@ -1,18 +1,163 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"goweb/go/database/helpers"
+	"io"
+	"sort"
+	"strings"
+
 	"github.com/Data-Corruption/lmdb-go/lmdb"
+	"golang.org/x/mod/semver"
 )
 
 type MigrationFunc func(txn *lmdb.Txn, dbi lmdb.DBI, schemas map[string]schema) error
 
+// Migrations holds one function per "fromVersion->toVersion" step. Steps don't need to exist for every
+// pair of versions, Migrate chains them, e.g. v1.0.0->v1.1.0->v1.2.0 for a user skipping v1.1.0.
+//
+// Down-migrations (e.g. "v1.1.0->v1.0.0") are optional. They let a binary open a db written by a newer
+// release, which happens when the installer rolls back a failed update. Without one Migrate refuses with
+// [ErrNewerConfig]. Since an older binary doesn't know the newer schema, a down step must only rely
+// on the schema it lands on, and has to ship in (or be backported to) the older release line.
 var Migrations = map[string]MigrationFunc{
-	"v0.0.1->v0.0.2": migrateV0_0_1toV0_0_2, // Example
+	// "v0.0.1->v0.0.2": migrateV0_0_1toV0_0_2, // Example, registering steps between unknown versions fails Verify
+	"v1.0.0->v1.1.0": migrateV1_0_0toV1_1_0,
 }
 
 // Example migration function
 func migrateV0_0_1toV0_0_2(txn *lmdb.Txn, dbi lmdb.DBI, schemas map[string]schema) error {
 	// Implement the migration logic here
 	// Use old schema to read data and new schema to write updated data
+	// Sensitive values are stored encrypted (see `sensitive.go`) and bound to their key, leave them as is or reset them
+	return nil
+}
+
+// migrateV1_0_0toV1_1_0 lets TLS paths still at the old "" default follow the new one in the data path.
+// lastUpdateCheck also got a computed default, its stored values are real check times so they're kept.
+func migrateV1_0_0toV1_1_0(txn *lmdb.Txn, dbi lmdb.DBI, schemas map[string]schema) error {
+	for _, key := range []string{"tlsKeyPath", "tlsCertPath"} {
+		data, err := txn.Get(dbi, []byte(key))
+		if lmdb.IsNotFound(err) || (err == nil && string(data) != `""`) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// deleted, Migrate writes the default of keys no step wrote
+		if err := txn.Del(dbi, []byte(key), nil); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// migrationPath returns the shortest chain of registered migration steps leading from one version to another.
+// Only steps heading towards the target (up or down) that don't overshoot it are considered,
+// ties are broken towards the versions closest to where the chain starts.
+func (cfg *Config) migrationPath(from, to string) ([]string, error) {
+	dir := semver.Compare(to, from) // 1 = upgrade, -1 = downgrade
+	next := map[string][]string{}   // version -> versions reachable in one step
+	for step := range cfg.Migrations {
+		a, b, ok := strings.Cut(step, "->")
+		if !ok || !semver.IsValid(a) || !semver.IsValid(b) {
+			return nil, fmt.Errorf("invalid migration key '%s', expected 'vX.Y.Z->vX.Y.Z'", step)
+		}
+		if semver.Compare(b, a) == dir && semver.Compare(to, b) != -dir {
+			next[a] = append(next[a], b)
+		}
+	}
+	for _, vs := range next {
+		sort.Slice(vs, func(i, j int) bool { return semver.Compare(vs[i], vs[j]) == -dir })
+	}
+
+	// breadth first search, prev doubles as the visited set
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 && queue[0] != to {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, n := range next[cur] {
+			if _, seen := prev[n]; !seen {
+				prev[n] = cur
+				queue = append(queue, n)
+			}
+		}
+	}
+	if _, ok := prev[to]; !ok {
+		return nil, fmt.Errorf("unsupported migration path: from '%s' to '%s'. No chain of registered migration functions connects them", from, to)
+	}
+	var path []string
+	for v := to; v != from; v = prev[v] {
+		path = append([]string{prev[v] + "->" + v}, path...)
+	}
+	return path, nil
+}
+
+// MigrationPlan describes what [Config.Migrate] would change, see [Config.PlanMigration].
+// Keys of profile overrides are listed as "profile/key".
+type MigrationPlan struct {
+	From      string   // stored version, empty if the config isn't initialized yet
+	To        string   // version of this binary
+	Steps     []string // registered migration steps, in order
+	Added     []string // keys that would be stored
+	Removed   []string // keys that would be deleted
+	Rewritten []string // keys whose stored value would change
+}
+
+var errDryRun = errors.New("dry run")
+
+// PlanMigration runs the migration in a write txn that is then aborted. Nothing is written and no backup is made.
+func (cfg *Config) PlanMigration(ctx context.Context) (*MigrationPlan, error) {
+	plan := &MigrationPlan{To: cfg.Version}
+	out := cfg.out
+	cfg.out = io.Discard
+	defer func() { cfg.out = out }()
+	err := cfg.DB.Update(func(txn *lmdb.Txn) error {
+		if err := helpers.GetAndUnmarshal(txn, cfg.DBI, []byte("version"), &plan.From); err != nil && !lmdb.IsNotFound(err) {
+			return fmt.Errorf("failed to get config version: %w", err)
+		}
+		if plan.From != "" && plan.From != cfg.Version {
+			plan.Steps, _ = cfg.migrationPath(plan.From, cfg.Version) // migrate reports a missing path
+		}
+		before, err := cfg.rawValues(txn)
+		if err != nil {
+			return err
+		}
+		if err := cfg.migrate(ctx, txn, false); err != nil {
+			return err
+		}
+		after, err := cfg.rawValues(txn)
+		if err != nil {
+			return err
+		}
+		for k, a := range after {
+			b, ok := before[k]
+			_, key, found := strings.Cut(k, "/")
+			if !found {
+				key = k
+			}
+			switch {
+			case !ok:
+				plan.Added = append(plan.Added, k)
+			case cfg.changed(key, b, a):
+				plan.Rewritten = append(plan.Rewritten, k)
+			}
+		}
+		for k := range before {
+			if _, ok := after[k]; !ok {
+				plan.Removed = append(plan.Removed, k)
+			}
+		}
+		return errDryRun // abort, nothing is committed
+	})
+	if !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	sort.Strings(plan.Added)
+	sort.Strings(plan.Removed)
+	sort.Strings(plan.Rewritten)
+	return plan, nil
+}
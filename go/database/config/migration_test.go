@@ -0,0 +1,123 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"goweb/go/database/datapath"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+func noopMigration(txn *lmdb.Txn, dbi lmdb.DBI, schemas map[string]schema) error { return nil }
+
+func TestMigrationPath(t *testing.T) {
+	record := map[string]MigrationFunc{
+		"v1.0.0->v1.1.0": noopMigration,
+		"v1.1.0->v1.2.0": noopMigration,
+		"v1.0.0->v1.2.0": noopMigration, // shortcut
+		"v1.1.0->v1.4.0": noopMigration, // overshoots v1.3.0
+		"v1.2.0->v1.3.0": noopMigration,
+		"v1.3.0->v1.2.0": noopMigration, // down
+	}
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+		err      bool
+	}{
+		{name: "single step", from: "v1.0.0", to: "v1.1.0", want: []string{"v1.0.0->v1.1.0"}},
+		{name: "shortest", from: "v1.0.0", to: "v1.2.0", want: []string{"v1.0.0->v1.2.0"}},
+		{name: "chain", from: "v1.0.0", to: "v1.3.0", want: []string{"v1.0.0->v1.2.0", "v1.2.0->v1.3.0"}},
+		{name: "no overshoot", from: "v1.1.0", to: "v1.3.0", want: []string{"v1.1.0->v1.2.0", "v1.2.0->v1.3.0"}},
+		{name: "down", from: "v1.3.0", to: "v1.2.0", want: []string{"v1.3.0->v1.2.0"}},
+		{name: "same version", from: "v1.2.0", to: "v1.2.0"},
+		{name: "no down step", from: "v1.2.0", to: "v1.1.0", err: true},
+		{name: "unknown start", from: "v0.9.0", to: "v1.1.0", err: true},
+		{name: "unreachable target", from: "v1.0.0", to: "v1.5.0", err: true},
+	}
+	cfg := &Config{Migrations: record}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := cfg.migrationPath(tt.from, tt.to)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("got %v, want an error", path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(path, tt.want) {
+				t.Fatalf("got %v, want %v", path, tt.want)
+			}
+		})
+	}
+
+	cfg = &Config{Migrations: map[string]MigrationFunc{"v1.0.0-v1.1.0": noopMigration}}
+	if _, err := cfg.migrationPath("v1.0.0", "v1.1.0"); err == nil {
+		t.Fatal("accepted an invalid migration key")
+	}
+}
+
+func TestMigrateNewerConfig(t *testing.T) {
+	type v1_0_0 struct {
+		Version string `cfg:"version,internal" default:"v1.0.0"`
+	}
+	type v1_1_0 struct {
+		Version string `cfg:"version,internal" default:"v1.1.0"`
+	}
+	schemas := map[string]schema{"v1.0.0": SchemaOf[v1_0_0](), "v1.1.0": SchemaOf[v1_1_0]()}
+	ctx, db := testDB(t)
+	openConfig(t, ctx, db, "v1.1.0", schemas, nil) // written by the newer release
+
+	for _, tt := range []struct {
+		name       string
+		migrations map[string]MigrationFunc
+		err        error
+	}{
+		{name: "no down step", err: ErrNewerConfig},
+		{name: "down step", migrations: map[string]MigrationFunc{"v1.1.0->v1.0.0": noopMigration}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := New("v1.0.0", schemas, tt.migrations, nil, db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.out = io.Discard
+			cfg.dataPath = datapath.FromContext(ctx)
+			if err := cfg.Migrate(ctx); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestMigrateV1_0_0toV1_1_0(t *testing.T) {
+	ctx, db := testDB(t)
+	old := openConfig(t, ctx, db, "v1.0.0", SchemaRecord, nil)
+	cert := filepath.Join(t.TempDir(), "cert.pem")
+	if err := os.WriteFile(cert, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(IntoContext(ctx, old), "tlsCertPath", cert); err != nil {
+		t.Fatal(err)
+	}
+	checked := lookup(t, old, "lastUpdateCheck")
+
+	cfg := openConfig(t, ctx, db, "v1.1.0", SchemaRecord, Migrations)
+	for key, want := range map[string]any{
+		"tlsKeyPath":      filepath.Join(cfg.dataPath, "tls", "key.pem"), // was the old default
+		"tlsCertPath":     cert,                                          // set by the user
+		"lastUpdateCheck": checked,
+	} {
+		if got := lookup(t, cfg, key); got != want {
+			t.Errorf("%s is %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// Keys holding structs, slices or maps can be read and written in parts with a path, e.g. "cors.origins[1]":
+// the key, then ".name" for a struct field (by its json name) or map entry, "[n]" for a slice / array element.
+// Setting the index one past the end of a slice appends to it. Missing map entries and nil pointers are created.
+//
+//	origins, err := config.GetPath[[]string](ctx, "cors.origins")
+//	err := config.SetPath(ctx, "cors.origins[1]", "https://x")
+//
+// The leaf is parsed / checked as its own type, then the whole key goes through its validators (see [At] and [Each])
+// and the cross-key rules as usual. The shell equivalent is `goweb config get|set cors.origins[1]`.
+// (cors is a hypothetical key holding {"origins": [...]}, the current schema only has plain keys.)
+
+type pathSegment struct {
+	name  string // field or map key, empty for an index
+	index int
+}
+
+func (s pathSegment) String() string {
+	if s.name == "" {
+		return "[" + strconv.Itoa(s.index) + "]"
+	}
+	return "." + s.name
+}
+
+// splitPath splits a path into its key and segments, a plain key has none.
+func splitPath(path string) (key string, segs []pathSegment, err error) {
+	i := strings.IndexAny(path, ".[")
+	if i < 0 {
+		return path, nil, nil
+	}
+	if i == 0 {
+		return "", nil, fmt.Errorf("invalid path '%s': missing key", path)
+	}
+	segs, err = parseSegments(path[i:])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid path '%s': %w", path, err)
+	}
+	return path[:i], segs, nil
+}
+
+// parseSegments parses the part of a path after the key, e.g. ".origins[1]".
+func parseSegments(s string) ([]pathSegment, error) {
+	var segs []pathSegment
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			end := strings.IndexAny(s[1:], ".[")
+			if end < 0 {
+				end = len(s) - 1
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty name")
+			}
+			segs = append(segs, pathSegment{name: s[1 : end+1]})
+			s = s[end+1:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ']'")
+			}
+			n, err := strconv.Atoi(s[1:end])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid index '%s'", s[1:end])
+			}
+			segs = append(segs, pathSegment{index: n})
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected '%c', expected '.' or '['", s[0])
+		}
+	}
+	return segs, nil
+}
+
+// joinSegments formats segs for error messages, e.g. "origins[1]".
+func joinSegments(segs []pathSegment) string {
+	var b strings.Builder
+	for _, s := range segs {
+		b.WriteString(s.String())
+	}
+	return strings.TrimPrefix(b.String(), ".")
+}
+
+// PathKey returns the key a path points into, e.g. "cors" for "cors.origins[1]".
+func PathKey(path string) string {
+	if i := strings.IndexAny(path, ".["); i > 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// fieldByName returns the index of the exported field of a struct type t with the given json name.
+// Like encoding/json, an exact match wins over a case-insensitive one.
+func fieldByName(t reflect.Type, name string) (int, bool) {
+	fold := -1
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+		if jsonName == name {
+			return i, true
+		}
+		if fold < 0 && strings.EqualFold(jsonName, name) {
+			fold = i
+		}
+	}
+	return fold, fold >= 0
+}
+
+// leafType returns the type found at segs inside t.
+func leafType(t reflect.Type, segs []pathSegment) (reflect.Type, error) {
+	for i, s := range segs {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch {
+		case s.name != "" && t.Kind() == reflect.Struct:
+			f, ok := fieldByName(t, s.name)
+			if !ok {
+				return nil, fmt.Errorf("%s has no field '%s'", t, s.name)
+			}
+			t = t.Field(f).Type
+		case s.name != "" && t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+			t = t.Elem()
+		case s.name == "" && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+			t = t.Elem()
+		case i == 0:
+			return nil, fmt.Errorf("cannot use %s on %s", s, t)
+		default:
+			return nil, fmt.Errorf("cannot use %s on %s at '%s'", s, t, joinSegments(segs[:i]))
+		}
+	}
+	return t, nil
+}
+
+// getPath returns the value found at segs inside v.
+func getPath(v reflect.Value, segs []pathSegment) (reflect.Value, error) {
+	if _, err := leafType(v.Type(), segs); err != nil {
+		return reflect.Value{}, err
+	}
+	for i, s := range segs {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, fmt.Errorf("'%s' is not set", joinSegments(segs[:i]))
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			f, _ := fieldByName(v.Type(), s.name)
+			v = v.Field(f)
+		case reflect.Map:
+			e := v.MapIndex(reflect.ValueOf(s.name).Convert(v.Type().Key()))
+			if !e.IsValid() {
+				return reflect.Value{}, fmt.Errorf("no entry '%s'", joinSegments(segs[:i+1]))
+			}
+			v = e
+		default: // slice or array
+			if s.index >= v.Len() {
+				return reflect.Value{}, fmt.Errorf("index out of range at '%s', length is %d", joinSegments(segs[:i+1]), v.Len())
+			}
+			v = v.Index(s.index)
+		}
+	}
+	return v, nil
+}
+
+// setPath returns a copy of v with the value at segs replaced by leaf, leaving v as is.
+func setPath(v reflect.Value, segs []pathSegment, leaf reflect.Value) (reflect.Value, error) {
+	if len(segs) == 0 {
+		if !leaf.Type().AssignableTo(v.Type()) {
+			return reflect.Value{}, fmt.Errorf("expected %s, got %s", v.Type(), leaf.Type())
+		}
+		return leaf, nil
+	}
+	t, s := v.Type(), segs[0]
+	switch t.Kind() {
+	case reflect.Pointer:
+		c := reflect.New(t.Elem())
+		if !v.IsNil() {
+			c.Elem().Set(v.Elem())
+		}
+		inner, err := setPath(c.Elem(), segs, leaf)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		c.Elem().Set(inner)
+		return c, nil
+	case reflect.Struct:
+		f, ok := fieldByName(t, s.name)
+		if !ok || s.name == "" {
+			return reflect.Value{}, fmt.Errorf("%s has no field '%s'", t, s.name)
+		}
+		c := reflect.New(t).Elem()
+		c.Set(v)
+		inner, err := setPath(c.Field(f), segs[1:], leaf)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		c.Field(f).Set(inner)
+		return c, nil
+	case reflect.Map:
+		if s.name == "" || t.Key().Kind() != reflect.String {
+			return reflect.Value{}, fmt.Errorf("cannot use %s on %s", s, t)
+		}
+		c := reflect.MakeMapWithSize(t, v.Len()+1)
+		for it := v.MapRange(); it.Next(); {
+			c.SetMapIndex(it.Key(), it.Value())
+		}
+		k := reflect.ValueOf(s.name).Convert(t.Key())
+		e := c.MapIndex(k)
+		if !e.IsValid() {
+			e = reflect.Zero(t.Elem())
+		}
+		inner, err := setPath(e, segs[1:], leaf)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		c.SetMapIndex(k, inner)
+		return c, nil
+	case reflect.Slice, reflect.Array:
+		if s.name != "" {
+			return reflect.Value{}, fmt.Errorf("cannot use %s on %s", s, t)
+		}
+		var c reflect.Value
+		if t.Kind() == reflect.Array {
+			c = reflect.New(t).Elem()
+			c.Set(v)
+		} else {
+			c = reflect.MakeSlice(t, v.Len(), v.Len()+1)
+			reflect.Copy(c, v)
+			if s.index == v.Len() {
+				c = reflect.Append(c, reflect.Zero(t.Elem()))
+			}
+		}
+		if s.index >= c.Len() {
+			return reflect.Value{}, fmt.Errorf("index %s out of range, length is %d", s, v.Len())
+		}
+		inner, err := setPath(c.Index(s.index), segs[1:], leaf)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		c.Index(s.index).Set(inner)
+		return c, nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s on %s", s, t)
+}
+
+// parseAs converts user input into a value of type t, see [value.Parse].
+func parseAs(t reflect.Type, raw string) (reflect.Value, error) {
+	ptr := reflect.New(t)
+	if t.Kind() == reflect.String {
+		ptr.Elem().SetString(raw)
+		return ptr.Elem(), nil
+	}
+	val, err := decodeAs(t, []byte(raw))
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("cannot parse %q as %s: %w", raw, t, err)
+	}
+	return val, nil
+}
+
+// decodeAs strictly decodes JSON into a value of type t, unknown struct fields, null and trailing data are errors.
+func decodeAs(t reflect.Type, data []byte) (reflect.Value, error) {
+	// json.Unmarshal treats null as a no-op, which would silently become the zero value
+	if string(bytes.TrimSpace(data)) == "null" {
+		return reflect.Value{}, fmt.Errorf("null is not a valid %s", t)
+	}
+	ptr := reflect.New(t)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(ptr.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	if dec.More() {
+		return reflect.Value{}, fmt.Errorf("unexpected trailing data")
+	}
+	return ptr.Elem(), nil
+}
+
+// GetPath returns the effective value at a path inside a key, e.g. "cors.origins". A plain key works like [Get].
+func GetPath[T any](ctx context.Context, path string) (T, error) {
+	var result T
+	if err := View(ctx, func(tx *Tx) (err error) {
+		result, err = TxGetPath[T](tx, path)
+		return err
+	}); err != nil {
+		return *new(T), fmt.Errorf("failed to get config path '%s': %w", path, err)
+	}
+	return result, nil
+}
+
+// SetPath replaces the value at a path inside a key and writes the key, e.g. "cors.origins[1]".
+func SetPath[T any](ctx context.Context, path string, val T) error {
+	if err := Update(ctx, func(tx *Tx) error {
+		return TxSetPath(tx, path, val)
+	}); err != nil {
+		return fmt.Errorf("failed to set config path '%s': %w", path, err)
+	}
+	return nil
+}
+
+// TxGetPath is [GetPath] within tx.
+func TxGetPath[T any](tx *Tx, path string) (T, error) {
+	path, err := tx.cfg.canonical(path)
+	if err != nil {
+		return *new(T), err
+	}
+	key, segs, err := splitPath(path)
+	if err != nil {
+		return *new(T), err
+	}
+	val, _, err := tx.resolvePath(key, segs)
+	if err != nil {
+		return *new(T), err
+	}
+	result, ok := val.(T)
+	if !ok {
+		return *new(T), fmt.Errorf("path '%s' is %T, not %s", path, val, reflect.TypeFor[T]())
+	}
+	return result, nil
+}
+
+// TxSetPath is [SetPath] within tx. Cross-key rules are checked once, when [Update] commits.
+func TxSetPath[T any](tx *Tx, path string, val T) error {
+	if tx.readOnly {
+		return fmt.Errorf("cannot set path '%s' in a read-only transaction", path)
+	}
+	path, err := tx.cfg.canonical(path)
+	if err != nil {
+		return err
+	}
+	key, segs, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	updated, err := tx.cfg.withPath(tx.txn, key, segs, reflect.ValueOf(&val).Elem(), nil)
+	if err != nil {
+		return err
+	}
+	return tx.cfg.set(tx.txn, key, updated)
+}
+
+// resolvePath returns the effective value at segs inside key and the layer it was resolved from.
+func (tx *Tx) resolvePath(key string, segs []pathSegment) (any, Source, error) {
+	v, err := tx.cfg.lookup(key)
+	if err != nil {
+		return nil, "", err
+	}
+	val, src, err := tx.resolve(key, v)
+	if err != nil || len(segs) == 0 {
+		return val, src, err
+	}
+	leaf, err := getPath(reflect.ValueOf(val), segs)
+	if err != nil {
+		return nil, src, fmt.Errorf("key '%s': %w", key, err)
+	}
+	return leaf.Interface(), src, nil
+}
+
+// withPath returns the value of key with leaf set at segs, starting from cur or, if it's nil, the stored value
+// (ignoring env overrides, they aren't written back). Nothing is written.
+func (cfg *Config) withPath(txn *lmdb.Txn, key string, segs []pathSegment, leaf reflect.Value, cur any) (any, error) {
+	v, err := cfg.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if cur == nil {
+		if cur, _, err = cfg.stored(txn, key, v); err != nil {
+			return nil, err
+		}
+	}
+	updated, err := setPath(reflect.ValueOf(cur), segs, leaf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for key '%s': %w", key, err)
+	}
+	return updated.Interface(), nil
+}
+
+// pathUpdate is a parsed `config set` argument addressing a path, see [Config.SetStrings].
+type pathUpdate struct {
+	path string
+	key  string
+	segs []pathSegment
+	leaf reflect.Value
+}
+
+// sortPathUpdates orders updates so indexes are applied in increasing order, e.g. "[9]" before "[10]",
+// which lets several appends to the same slice go through in one call.
+func sortPathUpdates(updates []pathUpdate) {
+	sort.Slice(updates, func(i, j int) bool {
+		a, b := updates[i].path, updates[j].path
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type testTLS struct {
+	Port int `json:"port"`
+}
+
+type testCORS struct {
+	Origins []string          `json:"origins"`
+	Headers map[string]string `json:"headers"`
+	Pair    [2]int            `json:"pair"`
+	TLS     *testTLS          `json:"tls"`
+}
+
+// segments parses a path without its key, e.g. "origins[1]".
+func segments(t *testing.T, path string) []pathSegment {
+	t.Helper()
+	if path != "" && path[0] != '[' {
+		path = "." + path
+	}
+	segs, err := parseSegments(path)
+	if err != nil {
+		t.Fatalf("failed to parse '%s': %s", path, err)
+	}
+	return segs
+}
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		path string
+		key  string
+		segs []pathSegment
+		err  bool
+	}{
+		{path: "port", key: "port"},
+		{path: "cors.origins[1]", key: "cors", segs: []pathSegment{{name: "origins"}, {index: 1}}},
+		{path: "cors.headers.x-a", key: "cors", segs: []pathSegment{{name: "headers"}, {name: "x-a"}}},
+		{path: "list[0][2]", key: "list", segs: []pathSegment{{index: 0}, {index: 2}}},
+		{path: ".origins", err: true},
+		{path: "cors..origins", err: true},
+		{path: "cors.", err: true},
+		{path: "cors[1", err: true},
+		{path: "cors[a]", err: true},
+		{path: "cors[-1]", err: true},
+		{path: "cors[1]x", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			key, segs, err := splitPath(tt.path)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("got key '%s' and %v, want an error", key, segs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != tt.key || !reflect.DeepEqual(segs, tt.segs) {
+				t.Fatalf("got key '%s' and %v, want '%s' and %v", key, segs, tt.key, tt.segs)
+			}
+		})
+	}
+}
+
+func TestGetPath(t *testing.T) {
+	cors := testCORS{Origins: []string{"a", "b"}, Headers: map[string]string{"x": "1"}, Pair: [2]int{1, 2}}
+	tests := []struct {
+		path string
+		want any
+		err  bool
+	}{
+		{path: "origins[1]", want: "b"},
+		{path: "Origins[0]", want: "a"}, // field names match case-insensitively like encoding/json
+		{path: "headers.x", want: "1"},
+		{path: "pair[1]", want: 2},
+		{path: "origins", want: []string{"a", "b"}},
+		{path: "origins[2]", err: true},    // out of range
+		{path: "pair[2]", err: true},       // out of range
+		{path: "headers.y", err: true},     // no entry
+		{path: "tls.port", err: true},      // nil pointer
+		{path: "origins.x", err: true},     // name on a slice
+		{path: "headers[0]", err: true},    // index on a map
+		{path: "pair[0].x", err: true},     // name on an int
+		{path: "missing", err: true},       // no such field
+		{path: "origins[0][0]", err: true}, // index on a string
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := getPath(reflect.ValueOf(cors), segments(t, tt.path))
+			if tt.err {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	base := testCORS{Origins: []string{"a"}, Headers: map[string]string{"x": "1"}}
+	tests := []struct {
+		name string
+		from testCORS
+		path string
+		leaf any
+		want testCORS
+		err  bool
+	}{
+		{name: "replace element", from: base, path: "origins[0]", leaf: "b",
+			want: testCORS{Origins: []string{"b"}, Headers: map[string]string{"x": "1"}}},
+		{name: "append", from: base, path: "origins[1]", leaf: "b",
+			want: testCORS{Origins: []string{"a", "b"}, Headers: map[string]string{"x": "1"}}},
+		{name: "append to nil slice", path: "origins[0]", leaf: "a", want: testCORS{Origins: []string{"a"}}},
+		{name: "past the end", from: base, path: "origins[2]", leaf: "c", err: true},
+		{name: "past the end of nil slice", path: "origins[1]", leaf: "a", err: true},
+		{name: "add map entry", from: base, path: "headers.y", leaf: "2",
+			want: testCORS{Origins: []string{"a"}, Headers: map[string]string{"x": "1", "y": "2"}}},
+		{name: "nil map", path: "headers.y", leaf: "2", want: testCORS{Headers: map[string]string{"y": "2"}}},
+		{name: "array element", path: "pair[1]", leaf: 7, want: testCORS{Pair: [2]int{0, 7}}},
+		{name: "array out of range", path: "pair[2]", leaf: 7, err: true},
+		{name: "nil pointer", path: "tls.port", leaf: 443, want: testCORS{TLS: &testTLS{Port: 443}}},
+		{name: "whole field", path: "origins", leaf: []string{"c"}, want: testCORS{Origins: []string{"c"}}},
+		{name: "wrong leaf type", from: base, path: "origins[0]", leaf: 1, err: true},
+		{name: "name on slice", from: base, path: "origins.x", leaf: "b", err: true},
+		{name: "index on map", from: base, path: "headers[0]", leaf: "b", err: true},
+		{name: "missing field", path: "missing", leaf: "b", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testCORS{Origins: append([]string(nil), tt.from.Origins...), Headers: map[string]string{}}
+			for k, v := range tt.from.Headers {
+				before.Headers[k] = v
+			}
+			got, err := setPath(reflect.ValueOf(tt.from), segments(t, tt.path), reflect.ValueOf(tt.leaf))
+			if tt.err {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Interface(), tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.from.Origins, before.Origins) || len(tt.from.Headers) != len(before.Headers) {
+				t.Fatalf("setPath modified its input: %+v", tt.from)
+			}
+		})
+	}
+}
+
+func TestSetPathWritesKey(t *testing.T) {
+	type v1_0_0 struct {
+		Version string   `cfg:"version,internal" default:"v1.0.0"`
+		CORS    testCORS `cfg:"cors"`
+	}
+	cfg := testConfig(t, "v1.0.0", map[string]schema{"v1.0.0": SchemaOf[v1_0_0]()}, nil)
+	ctx := IntoContext(cfg.ctx, cfg)
+
+	for i, origin := range []string{"https://a", "https://b"} {
+		if err := SetPath(ctx, fmt.Sprintf("cors.origins[%d]", i), origin); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SetPath(ctx, "cors.origins[0]", 1); err == nil {
+		t.Fatal("set an int into a list of strings")
+	}
+	origins, err := GetPath[[]string](ctx, "cors.origins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(origins, []string{"https://a", "https://b"}) {
+		t.Fatalf("got %v", origins)
+	}
+	if _, err := GetPath[int](ctx, "cors.origins[0]"); err == nil {
+		t.Fatal("got a string as an int")
+	}
+}
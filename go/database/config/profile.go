@@ -0,0 +1,254 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// ProfileEnv selects the active profile, like the root `--profile` flag.
+const ProfileEnv = EnvPrefix + "PROFILE"
+
+// Profiles are named, sparse overlays of the base config, e.g. "dev" only overriding port and logLevel.
+// With one active, reads resolve env -> profile -> base -> default and writes go to the profile.
+// Internal keys are never part of a profile.
+//
+// Each profile is a "name/" marker entry holding its creation time plus a "name/key" entry per overridden key.
+
+var profileNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
+// Profile is a profile and the keys it overrides.
+type Profile struct {
+	Name    string
+	Created time.Time
+	Keys    []string
+}
+
+// ProfileDiff is a key whose value differs between two profiles, see [Config.DiffProfiles].
+type ProfileDiff struct {
+	Key  string
+	A, B any
+}
+
+func profileKey(profile, key string) []byte {
+	return []byte(profile + "/" + key)
+}
+
+// splitProfileKey reverses profileKey, key is empty for the marker entry.
+func splitProfileKey(k []byte) (profile, key string) {
+	profile, key, _ = strings.Cut(string(k), "/")
+	return profile, key
+}
+
+// ActiveProfile returns the name of the active profile, empty if the base config is used.
+func (cfg *Config) ActiveProfile() string { return cfg.profile }
+
+// UseProfile makes name the active profile for this Config. An empty name switches back to the base config.
+func (cfg *Config) UseProfile(name string) error {
+	if name != "" {
+		if err := cfg.DB.View(func(txn *lmdb.Txn) error {
+			return cfg.profileExists(txn, name)
+		}); err != nil {
+			return err
+		}
+	}
+	cfg.profile = name
+	return nil
+}
+
+func (cfg *Config) profileExists(txn *lmdb.Txn, name string) error {
+	if _, err := txn.Get(cfg.ProfileDBI, profileKey(name, "")); err != nil {
+		if lmdb.IsNotFound(err) {
+			return fmt.Errorf("profile '%s' does not exist, create it with `config profile create %s`", name, name)
+		}
+		return fmt.Errorf("failed to read profile '%s': %w", name, err)
+	}
+	return nil
+}
+
+// Profiles returns every profile sorted by name.
+func (cfg *Config) Profiles() ([]Profile, error) {
+	var profiles []Profile
+	err := cfg.DB.View(func(txn *lmdb.Txn) error {
+		return cfg.eachProfileEntry(txn, "", func(profile, key string, data []byte) error {
+			if key == "" {
+				p := Profile{Name: profile}
+				json.Unmarshal(data, &p.Created) // informational only
+				profiles = append(profiles, p)
+				return nil
+			}
+			if len(profiles) > 0 && profiles[len(profiles)-1].Name == profile {
+				profiles[len(profiles)-1].Keys = append(profiles[len(profiles)-1].Keys, key)
+			}
+			return nil
+		})
+	})
+	return profiles, err
+}
+
+// CreateProfile creates an empty profile, or a copy of the profile from if it's not empty.
+func (cfg *Config) CreateProfile(ctx context.Context, name, from string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s', must match %s", name, profileNamePattern)
+	}
+	return cfg.update(changeSource(ctx), func(txn *lmdb.Txn) error {
+		if err := cfg.profileExists(txn, name); err == nil {
+			return fmt.Errorf("profile '%s' already exists", name)
+		}
+		created, err := json.Marshal(time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if err := txn.Put(cfg.ProfileDBI, profileKey(name, ""), created, 0); err != nil {
+			return fmt.Errorf("failed to create profile '%s': %w", name, err)
+		}
+		if from == "" {
+			return nil
+		}
+		if err := cfg.profileExists(txn, from); err != nil {
+			return err
+		}
+		// collect first, writing while iterating would move the cursor
+		overrides := map[string][]byte{}
+		if err := cfg.eachProfileEntry(txn, from, func(_, key string, data []byte) error {
+			if key != "" {
+				overrides[key] = data
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for key, data := range overrides {
+			if err := txn.Put(cfg.ProfileDBI, profileKey(name, key), data, 0); err != nil {
+				return fmt.Errorf("failed to copy key '%s' to profile '%s': %w", key, name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteProfile removes a profile and all of its overrides. The active profile can't be deleted.
+func (cfg *Config) DeleteProfile(ctx context.Context, name string) error {
+	if name == cfg.profile {
+		return fmt.Errorf("profile '%s' is active, run without --profile / %s to delete it", name, ProfileEnv)
+	}
+	return cfg.update(changeSource(ctx), func(txn *lmdb.Txn) error {
+		if err := cfg.profileExists(txn, name); err != nil {
+			return err
+		}
+		var keys [][]byte
+		if err := cfg.eachProfileEntry(txn, name, func(_, key string, _ []byte) error {
+			keys = append(keys, profileKey(name, key))
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := txn.Del(cfg.ProfileDBI, k, nil); err != nil {
+				return fmt.Errorf("failed to delete profile '%s': %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DiffProfiles returns the keys whose stored value differs between profiles a and b, an empty name being the base config.
+// Sensitive values are redacted, env overrides are ignored.
+func (cfg *Config) DiffProfiles(a, b string) ([]ProfileDiff, error) {
+	var diffs []ProfileDiff
+	err := cfg.DB.View(func(txn *lmdb.Txn) error {
+		for _, name := range []string{a, b} {
+			if name == "" {
+				continue
+			}
+			if err := cfg.profileExists(txn, name); err != nil {
+				return err
+			}
+		}
+		valuesA, err := cfg.storedValuesAs(txn, a)
+		if err != nil {
+			return err
+		}
+		valuesB, err := cfg.storedValuesAs(txn, b)
+		if err != nil {
+			return err
+		}
+		for _, key := range cfg.Keys() {
+			if reflect.DeepEqual(valuesA[key], valuesB[key]) {
+				continue
+			}
+			diffs = append(diffs, ProfileDiff{Key: key, A: cfg.Redact(key, valuesA[key]), B: cfg.Redact(key, valuesB[key])})
+		}
+		return nil
+	})
+	return diffs, err
+}
+
+// eachProfileEntry calls fn for every entry of profile, or of every profile if it's empty, in key order.
+func (cfg *Config) eachProfileEntry(txn *lmdb.Txn, profile string, fn func(profile, key string, data []byte) error) error {
+	cur, err := txn.OpenCursor(cfg.ProfileDBI)
+	if err != nil {
+		return fmt.Errorf("failed to open profile cursor: %w", err)
+	}
+	defer cur.Close()
+	var prefix []byte
+	op := uint(lmdb.First)
+	if profile != "" {
+		prefix, op = profileKey(profile, ""), lmdb.SetRange
+	}
+	k, v, err := cur.Get(prefix, nil, op)
+	for ; err == nil; k, v, err = cur.Get(nil, nil, lmdb.Next) {
+		if !bytes.HasPrefix(k, prefix) {
+			return nil
+		}
+		name, key := splitProfileKey(k)
+		if err := fn(name, key, v); err != nil {
+			return err
+		}
+	}
+	if lmdb.IsNotFound(err) {
+		return nil
+	}
+	return fmt.Errorf("failed to read profiles: %w", err)
+}
+
+// pruneProfiles drops overrides that no longer fit the current schema after a migration, printing what was dropped.
+// Profiles are sparse, so the base value (migrated) takes over for those keys.
+func (cfg *Config) pruneProfiles(txn *lmdb.Txn) error {
+	var stale [][]byte
+	if err := cfg.eachProfileEntry(txn, "", func(profile, key string, data []byte) error {
+		if key == "" {
+			return nil
+		}
+		v, ok := cfg.Schemas[cfg.Version][key]
+		reason := "key was removed"
+		if ok {
+			val, err := cfg.decode(key, v, data)
+			if err == nil {
+				err = v.Validate(val)
+			}
+			if err == nil && !v.IsInternal() {
+				return nil
+			}
+			reason = "value doesn't fit the new schema"
+		}
+		cfg.printf("config migration: dropping '%s' from profile '%s', %s\n", key, profile, reason)
+		stale = append(stale, profileKey(profile, key))
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, k := range stale {
+		if err := txn.Del(cfg.ProfileDBI, k, nil); err != nil {
+			return fmt.Errorf("failed to prune profiles: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ScaffoldMigration returns Go source for a migration function between two schema versions (see [Config.SchemaDiff]),
+// to be pasted into `migration.go` and finished by hand. Renames are done, type changes and changed defaults are left as TODOs.
+func (cfg *Config) ScaffoldMigration(from, to string) (string, error) {
+	if from == to {
+		return "", fmt.Errorf("nothing to migrate from '%s' to itself", from)
+	}
+	diff, err := cfg.SchemaDiff(from, to)
+	if err != nil {
+		return "", err
+	}
+	changes := diff.Changes
+	name := fmt.Sprintf("migrate%sto%s", goVersion(from), goVersion(to))
+	var b strings.Builder
+	fmt.Fprintf(&b, "// register in Migrations: %q: %s,\n\n", from+"->"+to, name)
+	fmt.Fprintf(&b, "func %s(txn *lmdb.Txn, dbi lmdb.DBI, schemas map[string]schema) error {\n", name)
+	if len(changes) == 0 {
+		b.WriteString("\t// no key changes, Migrate bumps the version\n")
+	}
+	for _, c := range changes {
+		fmt.Fprintf(&b, "\t// %s\n", c)
+		oldKey := c.Key
+		if c.Kind == ChangeRenamed {
+			oldKey = c.OldKey
+		}
+		if old, ok := cfg.Schemas[from][oldKey]; ok && old.IsSensitive() && (c.Kind == ChangeRenamed || c.Kind == ChangeType || c.Kind == ChangeDefault) {
+			b.WriteString("\t// TODO: sensitive values are encrypted and bound to their key, reset it instead (delete it, Migrate writes the default)\n")
+			continue
+		}
+		switch c.Kind {
+		case ChangeAdded:
+			b.WriteString("\t// Migrate writes the default of keys no step wrote, write a value here to derive it from old keys instead\n")
+		case ChangeRemoved:
+			b.WriteString("\t// left in the db so a rolled back binary still finds it, see PruneUnknownKeys\n")
+		case ChangeRenamed:
+			fmt.Fprintf(&b, "\t// keep scripts using the old name working with `alias:\"%s@<last version accepting it>\"` on the new field\n", c.OldKey)
+			fmt.Fprintf(&b, "\tif data, err := txn.Get(dbi, []byte(%q)); err == nil {\n", c.OldKey)
+			if c.OldType != c.NewType {
+				fmt.Fprintf(&b, "\t\t// TODO: data holds a %s, convert it to %s\n", localType(c.OldType), localType(c.NewType))
+			}
+			b.WriteString("\t\t// copied, data is only valid until the next write\n")
+			fmt.Fprintf(&b, "\t\tif err := txn.Put(dbi, []byte(%q), append([]byte(nil), data...), 0); err != nil {\n\t\t\treturn err\n\t\t}\n", c.Key)
+			fmt.Fprintf(&b, "\t\tif err := txn.Del(dbi, []byte(%q), nil); err != nil {\n\t\t\treturn err\n\t\t}\n", c.OldKey)
+			b.WriteString("\t} else if !lmdb.IsNotFound(err) {\n\t\treturn err\n\t}\n")
+		case ChangeType:
+			v := goIdent(c.Key)
+			fmt.Fprintf(&b, "\tvar %s %s\n", v, localType(c.OldType))
+			fmt.Fprintf(&b, "\tif err := helpers.GetAndUnmarshal(txn, dbi, []byte(%q), &%s); err != nil && !lmdb.IsNotFound(err) {\n\t\treturn err\n\t}\n", c.Key, v)
+			fmt.Fprintf(&b, "\tvar new%s %s // TODO: convert %s\n", strings.ToUpper(v[:1])+v[1:], localType(c.NewType), v)
+			fmt.Fprintf(&b, "\tif err := helpers.MarshalAndPut(txn, dbi, []byte(%q), new%s); err != nil {\n\t\treturn err\n\t}\n", c.Key, strings.ToUpper(v[:1])+v[1:])
+		case ChangeDefault:
+			data, err := json.Marshal(c.OldDefault)
+			if c.OldDefaultDoc != "" || err != nil {
+				b.WriteString("\t// stored values are kept, TODO: overwrite values still at the old default if they should follow it\n")
+				break
+			}
+			b.WriteString("\t// values still at the old default follow the new one, TODO: remove this if they should be kept\n")
+			fmt.Fprintf(&b, "\tif data, err := txn.Get(dbi, []byte(%q)); err == nil && string(data) == %q {\n", c.Key, data)
+			b.WriteString("\t\t// deleted, Migrate writes the default of keys no step wrote\n")
+			fmt.Fprintf(&b, "\t\tif err := txn.Del(dbi, []byte(%q), nil); err != nil {\n\t\t\treturn err\n\t\t}\n", c.Key)
+			b.WriteString("\t} else if err != nil && !lmdb.IsNotFound(err) {\n\t\treturn err\n\t}\n")
+		}
+	}
+	b.WriteString("\treturn nil\n}\n")
+	return b.String(), nil
+}
+
+// goVersion turns "v1.2.3" into "V1_2_3" for function names.
+func goVersion(v string) string {
+	return "V" + strings.NewReplacer(".", "_", "-", "_", "+", "_").Replace(strings.TrimPrefix(v, "v"))
+}
+
+// localType drops the package qualifier of types declared in this package, the scaffold is pasted into it.
+func localType(name string) string {
+	return strings.ReplaceAll(name, "config.", "")
+}
+
+// goIdent turns a key into a Go identifier, e.g. "tls-key" into "tlsKey".
+func goIdent(key string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range key {
+		switch {
+		case r == '_' || r == '-' || r == '.':
+			upper = b.Len() > 0
+		case upper:
+			b.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -1,6 +1,9 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 /*
 Once used in a released version, this struct cannot be changed.
@@ -15,38 +18,96 @@ type Example struct {
 }
 */
 
+// schemaV1_0_0 is the v1.0.0 schema, see [SchemaOf]. Released, don't change it.
+type schemaV1_0_0 struct {
+	Version         string `cfg:"version,internal" default:"v1.0.0" desc:"Schema version of the stored config, bumped by migrations."`
+	LogLevel        string `cfg:"logLevel" default:"warn" desc:"Minimum level of messages written to the log."`
+	Port            int    `cfg:"port,restart" default:"8080" desc:"Port the HTTP server listens on."`
+	UseTLS          bool   `cfg:"useTLS,restart" desc:"Serve HTTPS using tlsKeyPath and tlsCertPath instead of plain HTTP."`
+	TLSKeyPath      string `cfg:"tlsKeyPath,restart" desc:"Path to the PEM encoded TLS private key."`
+	TLSCertPath     string `cfg:"tlsCertPath,restart" desc:"Path to the PEM encoded TLS certificate."`
+	UpdateNotify    bool   `cfg:"updateNotify" default:"true" desc:"Print a notice when a newer release is available."`
+	LastUpdateCheck string `cfg:"lastUpdateCheck,internal" unit:"RFC 3339 time" desc:"When the daily update check last ran."`
+	UpdateAvailable bool   `cfg:"updateAvailable,internal" desc:"Whether the last update check found a newer release."`
+}
+
+// schemaV1_1_0 is the v1.1.0 schema: the TLS paths default to files in the data path and lastUpdateCheck
+// to the time it's initialized, see `defaults.go`. Released, so copy it to a new struct for the next version.
+type schemaV1_1_0 struct {
+	Version         string `cfg:"version,internal" default:"v1.1.0" desc:"Schema version of the stored config, bumped by migrations."`
+	LogLevel        string `cfg:"logLevel" default:"warn" desc:"Minimum level of messages written to the log."`
+	Port            int    `cfg:"port,restart" default:"8080" desc:"Port the HTTP server listens on."`
+	UseTLS          bool   `cfg:"useTLS,restart" desc:"Serve HTTPS using tlsKeyPath and tlsCertPath instead of plain HTTP."`
+	TLSKeyPath      string `cfg:"tlsKeyPath,restart" desc:"Path to the PEM encoded TLS private key, must exist when useTLS is set."`
+	TLSCertPath     string `cfg:"tlsCertPath,restart" desc:"Path to the PEM encoded TLS certificate, must exist when useTLS is set."`
+	UpdateNotify    bool   `cfg:"updateNotify" default:"true" desc:"Print a notice when a newer release is available."`
+	LastUpdateCheck string `cfg:"lastUpdateCheck,internal" unit:"RFC 3339 time" desc:"When the daily update check last ran."`
+	UpdateAvailable bool   `cfg:"updateAvailable,internal" desc:"Whether the last update check found a newer release."`
+}
+
 // Version is the current version of the schema
-const Version = "v1.0.0"
+const Version = "v1.1.0"
 
-// key -> default value
-type schema map[string]valueInterface
+// key -> default value and metadata (description, unit, internal, restart, see `value` in `config.go`).
+// Derive it from a struct with [SchemaOf], see `struct.go`, or write it by hand.
+type schema map[string]*value
 
 // SchemaRecord is a version -> schema map of all released and the current schema. For defaults and migration purposes.
 // After making changes to the schema, before the next release you must add a new version entry to this variable
 // and migration funcs for it in `migration.go`. The newest version is assumed to be the current version.
 var SchemaRecord = map[string]schema{
-	"v1.0.0": {
-		"version":         &value[string]{"v1.0.0"},
-		"logLevel":        &value[string]{"warn"},
-		"port":            &value[int]{8080},
-		"useTLS":          &value[bool]{false},
-		"tlsKeyPath":      &value[string]{""},
-		"tlsCertPath":     &value[string]{""},
-		"updateNotify":    &value[bool]{true},
-		"lastUpdateCheck": &value[string]{time.Now().Format(time.RFC3339)},
-		"updateAvailable": &value[bool]{false},
-	},
+	"v1.1.0": SchemaOf[schemaV1_1_0](
+		Checks("version", Match(`^v\d+\.\d+\.\d+$`)),
+		Checks("logLevel", OneOf("debug", "info", "warn", "error", "none")),
+		Checks("port", Range(1, 65535)),
+		DefaultFunc("tlsKeyPath", DataPathFile("tls", "key.pem"), "<data path>/tls/key.pem"),
+		DefaultFunc("tlsCertPath", DataPathFile("tls", "cert.pem"), "<data path>/tls/cert.pem"),
+		DefaultFunc("lastUpdateCheck", Now, "time of initialization"),
+	),
+	"v1.0.0": SchemaOf[schemaV1_0_0](
+		Checks("version", Match(`^v\d+\.\d+\.\d+$`)),
+		Checks("logLevel", OneOf("debug", "info", "warn", "error", "none")),
+		Checks("port", Range(1, 65535)),
+		Checks("tlsKeyPath", FileExists()),
+		Checks("tlsCertPath", FileExists()),
+		Default("lastUpdateCheck", time.Now().Format(time.RFC3339)),
+	),
 	/*
 		"v0.0.2": {
-			"version": &value[string]{"v0.0.2"},
-			"example1": &value[bool]{true},
-			"example3": &value[ExampleV2]{ExampleV2{"value"}},
+			"version": &value{t: reflect.TypeFor[string](), d: "v0.0.2"},
+			"example1": &value{t: reflect.TypeFor[bool](), d: true},
+			"example3": &value{t: reflect.TypeFor[ExampleV2](), d: ExampleV2{"value"}},
+			"authToken": &value{t: reflect.TypeFor[string](), d: "", sensitive: true}, // encrypted at rest, redacted in output
 		},
 		"v0.0.1": {
-			"version": &value[string]{"v0.0.1"},
-			"example1": &value[string]{"value"},
-			"example2": &value[int]{0},
-			"example3": &value[Example]{Example{1}},
+			"version": &value{t: reflect.TypeFor[string](), d: "v0.0.1"},
+			"example1": &value{t: reflect.TypeFor[string](), d: "value"},
+			"example2": &value{t: reflect.TypeFor[int](), d: 0},
+			"example3": &value{t: reflect.TypeFor[Example](), d: Example{1}},
 		},
 	*/
 }
+
+// RuleRecord is a version -> cross-key rules map. Per-key checks live on the schema values themselves.
+var RuleRecord = map[string][]Rule{
+	"v1.1.0": {tlsRequiresPaths},
+	"v1.0.0": {tlsRequiresPaths},
+}
+
+// tlsRequiresPaths checks the TLS files exist when useTLS is set. The paths default to files in the data path
+// that usually don't exist, so it can't be a per-key check.
+func tlsRequiresPaths(values map[string]any) error {
+	if useTLS, _ := values["useTLS"].(bool); !useTLS {
+		return nil
+	}
+	for _, key := range []string{"tlsKeyPath", "tlsCertPath"} {
+		path, _ := values[key].(string)
+		if path == "" {
+			return fmt.Errorf("useTLS requires %s to be set", key)
+		}
+		if err := FileExists()(path); err != nil {
+			return fmt.Errorf("useTLS requires %s to exist: %w", key, err)
+		}
+	}
+	return nil
+}
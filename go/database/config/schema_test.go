@@ -0,0 +1,9 @@
+package config_test
+
+import (
+	"testing"
+
+	"goweb/go/database/config/configtest"
+)
+
+func TestSchema(t *testing.T) { configtest.Verify(t) }
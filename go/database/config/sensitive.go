@@ -0,0 +1,114 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// KeyFileName is the name of the file in the data directory holding the key sensitive values are encrypted with.
+// Losing it makes stored sensitive values unreadable, they'll need to be reset.
+const KeyFileName = "config.key"
+
+// Redacted replaces sensitive values anywhere config is displayed.
+const Redacted = "[REDACTED]"
+
+const encPrefix = "enc:v1:" // prefix of encrypted values, stored as a JSON string
+
+// LoadKey reads the encryption key for sensitive values from path, creating it if it doesn't exist.
+func (cfg *Config) LoadKey(path string) error {
+	key, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return fmt.Errorf("failed to generate config key: %w", err)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create config key file: %w", err)
+		}
+		if _, err := f.Write(key); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write config key file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write config key file: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to read config key file: %w", err)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("config key file %s is corrupt: expected 32 bytes, got %d", path, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	cfg.aead, err = cipher.NewGCM(block)
+	return err
+}
+
+// Redact returns [Redacted] in place of val if key is sensitive.
+// Use this before displaying or serving config values.
+func (cfg *Config) Redact(key string, val any) any {
+	root := PathKey(key)
+	if k, _, ok := cfg.findAlias(root); ok {
+		root = k
+	}
+	if v, ok := cfg.Schemas[cfg.Version][root]; ok && v.IsSensitive() {
+		return Redacted
+	}
+	return val
+}
+
+// encode marshals val for storage, encrypting it if the key is sensitive.
+func (cfg *Config) encode(key string, v *value, val any) ([]byte, error) {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, fmt.Errorf("marshal error for key '%s': %w", key, err)
+	}
+	if !v.IsSensitive() {
+		return data, nil
+	}
+	if cfg.aead == nil {
+		return nil, fmt.Errorf("cannot store sensitive key '%s': no encryption key loaded", key)
+	}
+	nonce := make([]byte, cfg.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := cfg.aead.Seal(nonce, nonce, data, []byte(key)) // key as AD so values can't be swapped between keys
+	return json.Marshal(encPrefix + base64.StdEncoding.EncodeToString(sealed))
+}
+
+// decode reverses encode. Plaintext values of sensitive keys (e.g. written before the key was marked
+// sensitive) are still accepted, they're encrypted on the next write.
+func (cfg *Config) decode(key string, v *value, data []byte) (any, error) {
+	if !v.IsSensitive() {
+		return v.Decode(key, data)
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil || !strings.HasPrefix(s, encPrefix) {
+		return v.Decode(key, data)
+	}
+	if cfg.aead == nil {
+		return nil, fmt.Errorf("cannot read sensitive key '%s': no encryption key loaded", key)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, encPrefix))
+	if err != nil || len(sealed) < cfg.aead.NonceSize() {
+		return nil, fmt.Errorf("sensitive key '%s' has a malformed encrypted value", key)
+	}
+	nonce, ciphertext := sealed[:cfg.aead.NonceSize()], sealed[cfg.aead.NonceSize():]
+	plain, err := cfg.aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt key '%s', was %s replaced? reset the key to fix: %w", key, KeyFileName, err)
+	}
+	return v.Decode(key, plain)
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+type sensitiveSchema struct {
+	Version string `cfg:"version,internal" default:"v1.0.0"`
+	Token   string `cfg:"token,sensitive"`
+	Other   string `cfg:"other,sensitive"`
+}
+
+func TestSensitiveRoundTrip(t *testing.T) {
+	schemas := map[string]schema{"v1.0.0": SchemaOf[sensitiveSchema]()}
+	ctx, db := testDB(t)
+	cfg := openConfig(t, ctx, db, "v1.0.0", schemas, nil)
+	ctx = IntoContext(ctx, cfg)
+	for _, token := range []string{"first-secret", "second-secret"} {
+		if err := Set(ctx, "token", token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stored []byte
+	if err := cfg.DB.View(func(txn *lmdb.Txn) (err error) {
+		stored, err = txn.Get(cfg.DBI, []byte("token"))
+		stored = bytes.Clone(stored)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(stored), `"`+encPrefix) || strings.Contains(string(stored), "secret") {
+		t.Fatalf("token stored unencrypted: %s", stored)
+	}
+	if got := lookup(t, cfg, "token"); got != "second-secret" {
+		t.Fatalf("got %v, want second-secret", got)
+	}
+
+	// history keeps the encrypted form, decrypted like stored values
+	entries, err := cfg.History("token", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("got %d history entries, want at least 2", len(entries))
+	}
+	v := schemas["v1.0.0"]["token"]
+	for _, e := range entries {
+		if strings.Contains(string(e.Old)+string(e.New), "secret") {
+			t.Fatalf("history entry #%d holds a plaintext token: %s -> %s", e.ID, e.Old, e.New)
+		}
+	}
+	for raw, want := range map[string]string{string(entries[0].Old): "first-secret", string(entries[0].New): "second-secret"} {
+		got, err := cfg.decode("token", v, []byte(raw))
+		if err != nil || got != want {
+			t.Fatalf("decrypted history value %s to %v, %v, want %s", raw, got, err, want)
+		}
+	}
+	if _, err := cfg.Revert(ctx, entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := lookup(t, cfg, "token"); got != "first-secret" {
+		t.Fatalf("got %v after revert, want first-secret", got)
+	}
+
+	// values are bound to their key, a value copied to another key doesn't decrypt
+	if _, err := cfg.decode("other", schemas["v1.0.0"]["other"], stored); err == nil {
+		t.Fatal("decrypted the value of token as other")
+	}
+	// plaintext values, e.g. written before the key was marked sensitive, are still read
+	if got, err := cfg.decode("token", v, []byte(`"plain"`)); err != nil || got != "plain" {
+		t.Fatalf("got %v, %v for a plaintext value", got, err)
+	}
+}
+
+func TestSensitiveWrongKey(t *testing.T) {
+	schemas := map[string]schema{"v1.0.0": SchemaOf[sensitiveSchema]()}
+	ctx, db := testDB(t)
+	cfg := openConfig(t, ctx, db, "v1.0.0", schemas, nil)
+	if err := Set(IntoContext(ctx, cfg), "token", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := cfg.History("token", 1)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("got %d history entries, %v", len(entries), err)
+	}
+
+	other, err := New("v1.0.0", schemas, nil, nil, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.LoadKey(filepath.Join(t.TempDir(), KeyFileName)); err != nil { // a new key, e.g. the key file was lost
+		t.Fatal(err)
+	}
+	if _, _, err := other.Lookup("token"); err == nil || !strings.Contains(err.Error(), KeyFileName) {
+		t.Fatalf("got %v, want a decryption error naming %s", err, KeyFileName)
+	}
+	if _, err := other.decode("token", schemas["v1.0.0"]["token"], entries[0].New); err == nil {
+		t.Fatal("decrypted a history value with the wrong key")
+	}
+	if _, _, err := cfg.Lookup("token"); err != nil {
+		t.Fatalf("right key failed: %s", err)
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Schemas can be derived from a struct per version instead of written as a map, see [SchemaOf]:
+//
+//	type schemaV1_1_0 struct {
+//		Version string `cfg:"version,internal" default:"v1.1.0" desc:"Schema version of the stored config."`
+//		Port    int    `cfg:"port,restart" default:"8080" desc:"Port the HTTP server listens on."`
+//		Token   string `cfg:"authToken,sensitive"`
+//	}
+//
+//	"v1.1.0": SchemaOf[schemaV1_1_0](Checks("port", Range(1, 65535))),
+//
+// Every exported field needs a `cfg:"key[,internal][,sensitive][,restart]"` tag, `cfg:"-"` skips one.
+// `default` is parsed like `config set` input (strings verbatim, anything else as JSON), fields without one
+// default to their zero value. `desc` and `unit` are shown by `config describe`, `alias:"oldName@v1.4.0"` and
+// `deprecated:"notice"` are explained in `alias.go`. Validators and computed defaults can't be tags, pass them
+// as [Checks] / [DefaultFunc]. Once a version is released its struct is the contract, copy it for the next
+// version and let `goweb config scaffold FROM TO` write the migration skeleton.
+
+// FieldOption adds what a struct tag can't hold to a key of a [SchemaOf] schema.
+type FieldOption struct {
+	key   string
+	t     reflect.Type
+	apply func(v *value)
+}
+
+// Checks attaches validators to a key, F must be the type of its field.
+func Checks[F any](key string, checks ...Validator[F]) FieldOption {
+	return FieldOption{key: key, t: reflect.TypeFor[F](), apply: func(v *value) {
+		for _, check := range checks {
+			v.checks = append(v.checks, func(val any) error { return check(val.(F)) })
+		}
+	}}
+}
+
+// DefaultFunc computes the default of a key instead of its `default` tag, see `defaults.go`.
+// doc describes how it's derived for docs, e.g. "<data path>/tls/cert.pem".
+func DefaultFunc[F any](key string, df func(ctx context.Context) F, doc string) FieldOption {
+	return FieldOption{key: key, t: reflect.TypeFor[F](), apply: func(v *value) {
+		v.df = func(ctx context.Context) any { return df(ctx) }
+		v.dfDoc = doc
+	}}
+}
+
+// Default sets a static default a `default` tag can't hold, e.g. one computed at package init.
+func Default[F any](key string, d F) FieldOption {
+	return FieldOption{key: key, t: reflect.TypeFor[F](), apply: func(v *value) { v.d = d }}
+}
+
+// SchemaOf derives a schema from the tagged fields of T. It's meant for SchemaRecord and panics on a bad tag,
+// a default that doesn't parse or an option for a missing key or of the wrong type.
+func SchemaOf[T any](options ...FieldOption) schema {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config: SchemaOf %s: not a struct", t))
+	}
+	s := schema{}
+	values := map[string]*value{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		key, opts, ok := tagKey(f)
+		if !ok {
+			panic(fmt.Sprintf("config: SchemaOf %s: field %s has no cfg tag, use `cfg:\"-\"` to skip it", t, f.Name))
+		}
+		if key == "-" {
+			continue
+		}
+		if _, dup := s[key]; dup {
+			panic(fmt.Sprintf("config: SchemaOf %s: key '%s' is declared more than once", t, key))
+		}
+		v := &value{field: f.Name, t: f.Type, d: reflect.Zero(f.Type).Interface(), desc: f.Tag.Get("desc"), unit: f.Tag.Get("unit"),
+			deprecated: f.Tag.Get("deprecated")}
+		for _, opt := range opts {
+			switch opt {
+			case "internal":
+				v.internal = true
+			case "sensitive":
+				v.sensitive = true
+			case "restart":
+				v.restart = true
+			default:
+				panic(fmt.Sprintf("config: SchemaOf %s: field %s has unknown cfg option '%s'", t, f.Name, opt))
+			}
+		}
+		if raw, ok := f.Tag.Lookup("default"); ok {
+			d, err := parseAs(f.Type, raw)
+			if err != nil {
+				panic(fmt.Sprintf("config: SchemaOf %s: field %s: invalid default: %s", t, f.Name, err))
+			}
+			v.d = d.Interface()
+		}
+		if tag, ok := f.Tag.Lookup("alias"); ok {
+			aliases, err := parseAliases(tag)
+			if err != nil {
+				panic(fmt.Sprintf("config: SchemaOf %s: field %s: %s", t, f.Name, err))
+			}
+			v.aliases = aliases
+		}
+		s[key], values[key] = v, v
+	}
+	for _, opt := range options {
+		v, ok := values[opt.key]
+		if !ok {
+			panic(fmt.Sprintf("config: SchemaOf %s: option for unknown key '%s'", t, opt.key))
+		}
+		if v.t != opt.t {
+			panic(fmt.Sprintf("config: SchemaOf %s: option for key '%s' is for %s, the field is %s", t, opt.key, opt.t, v.t))
+		}
+		opt.apply(v)
+	}
+	return s
+}
+
+// tagKey returns the config key and options of a field's `cfg` tag, ok is false if it has none.
+func tagKey(f reflect.StructField) (key string, opts []string, ok bool) {
+	tag, ok := f.Tag.Lookup("cfg")
+	if !ok {
+		return "", nil, false
+	}
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:], true
+}
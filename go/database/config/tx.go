@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// Tx is a config transaction bound to a single LMDB txn. Use it through [TxGet] and [TxSet]
+// inside [Update] or [View], it must not be used after the callback returns.
+type Tx struct {
+	cfg      *Config
+	txn      *lmdb.Txn
+	readOnly bool
+}
+
+// Update runs fn in a write transaction. Writes made with [TxSet] are only visible to other
+// processes once fn returns nil and the cross-key rules pass, otherwise nothing is written.
+//
+// Changed keys are recorded in the history, see [ChangeSourceIntoContext].
+// Don't call Get/Set/Update from within fn, writes are serialized and it would deadlock.
+func Update(ctx context.Context, fn func(tx *Tx) error) error {
+	cfg := FromContext(ctx)
+	if cfg == nil {
+		return fmt.Errorf("config not found in context")
+	}
+	return cfg.update(changeSource(ctx), func(txn *lmdb.Txn) error {
+		if err := fn(&Tx{cfg: cfg, txn: txn}); err != nil {
+			return err
+		}
+		return cfg.checkRulesTxn(txn)
+	})
+}
+
+// View runs fn in a read-only transaction, giving it a consistent snapshot across keys.
+func View(ctx context.Context, fn func(tx *Tx) error) error {
+	cfg := FromContext(ctx)
+	if cfg == nil {
+		return fmt.Errorf("config not found in context")
+	}
+	return cfg.DB.View(func(txn *lmdb.Txn) error {
+		return fn(&Tx{cfg: cfg, txn: txn, readOnly: true})
+	})
+}
+
+// TxGet returns the effective value of a key (env, then db, then default), including writes made earlier in tx.
+func TxGet[T any](tx *Tx, key string) (T, error) {
+	key, err := tx.cfg.canonical(key)
+	if err != nil {
+		return *new(T), err
+	}
+	v, err := typed[T](tx.cfg, key)
+	if err != nil {
+		return *new(T), err
+	}
+	rawValue, _, err := tx.resolve(key, v)
+	if err != nil {
+		return *new(T), err
+	}
+	result, ok := rawValue.(T)
+	if !ok {
+		return *new(T), fmt.Errorf("stored value for key %s is not of expected type", key)
+	}
+	return result, nil
+}
+
+// TxSet validates and writes a key in tx. Cross-key rules are checked once, when [Update] commits.
+func TxSet[T any](tx *Tx, key string, val T) error {
+	if tx.readOnly {
+		return fmt.Errorf("cannot set key '%s' in a read-only transaction", key)
+	}
+	key, err := tx.cfg.canonical(key)
+	if err != nil {
+		return err
+	}
+	if _, err := typed[T](tx.cfg, key); err != nil {
+		return err
+	}
+	return tx.cfg.set(tx.txn, key, val)
+}
+
+// resolve is [Config.resolve], served from the value cache in read-only txns.
+func (tx *Tx) resolve(key string, v *value) (any, Source, error) {
+	if tx.readOnly {
+		return tx.cfg.resolveCached(tx.txn, key, v)
+	}
+	return tx.cfg.resolve(tx.txn, key, v)
+}
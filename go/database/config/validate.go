@@ -0,0 +1,211 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+)
+
+// Validator checks a single value before it is written.
+type Validator[T any] func(T) error
+
+// Rule checks relationships between keys, e.g. "useTLS requires both paths".
+// It receives the full set of values for a schema version, keyed by config key.
+type Rule func(values map[string]any) error
+
+// Range returns a validator that requires min <= v <= max.
+func Range[T cmp.Ordered](min, max T) Validator[T] {
+	return func(v T) error {
+		if v < min || v > max {
+			return fmt.Errorf("must be between %v and %v, got %v", min, max, v)
+		}
+		return nil
+	}
+}
+
+// OneOf returns a validator that requires the value to be one of the given options.
+func OneOf[T comparable](options ...T) Validator[T] {
+	return func(v T) error {
+		if !slices.Contains(options, v) {
+			return fmt.Errorf("must be one of %v, got %v", options, v)
+		}
+		return nil
+	}
+}
+
+// Match returns a validator that requires the value to match the given regular expression.
+func Match(pattern string) Validator[string] {
+	re := regexp.MustCompile(pattern)
+	return func(v string) error {
+		if !re.MatchString(v) {
+			return fmt.Errorf("must match %s, got %q", pattern, v)
+		}
+		return nil
+	}
+}
+
+// FileExists returns a validator that requires a non-empty path to point at an existing regular file.
+// Empty paths are allowed so optional paths can be left unset, use a [Rule] to require them.
+func FileExists() Validator[string] {
+	return func(path string) error {
+		if path == "" {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("file %q is not accessible: %w", path, err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%q is not a regular file", path)
+		}
+		return nil
+	}
+}
+
+// URL returns a validator that requires an absolute URL using one of the given schemes, e.g. URL("http", "https").
+// Empty strings are allowed, like [FileExists].
+func URL(schemes ...string) Validator[string] {
+	return func(v string) error {
+		if v == "" {
+			return nil
+		}
+		u, err := url.Parse(v)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("must be an absolute URL, got %q", v)
+		}
+		if len(schemes) > 0 && !slices.Contains(schemes, u.Scheme) {
+			return fmt.Errorf("URL scheme must be one of %v, got %q", schemes, u.Scheme)
+		}
+		return nil
+	}
+}
+
+// Each returns a validator that runs checks on every element of a slice, e.g. Each(URL("https")) for []string.
+func Each[T any](checks ...Validator[T]) Validator[[]T] {
+	return func(v []T) error {
+		for i, elem := range v {
+			for _, check := range checks {
+				if err := check(elem); err != nil {
+					return &leafError{path: fmt.Sprintf("[%d]", i), err: err}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// At returns a validator for a nested value that runs checks on the value found at path inside it, so e.g.
+// `config set cors.origins[1] ...` fails with an error pointing at the leaf. Path uses the syntax of `path.go`
+// without the key, e.g. At[CORS]("origins", Each(URL())). Unset paths (nil pointers, missing map entries) are skipped.
+// It panics if path doesn't lead to an L inside T, like regexp.MustCompile.
+func At[T, L any](path string, checks ...Validator[L]) Validator[T] {
+	if path != "" && path[0] != '[' {
+		path = "." + path
+	}
+	segs, err := parseSegments(path)
+	if err != nil {
+		panic(fmt.Sprintf("config.At: invalid path '%s': %v", path, err))
+	}
+	t, err := leafType(reflect.TypeFor[T](), segs)
+	if err != nil {
+		panic(fmt.Sprintf("config.At: %v", err))
+	}
+	if t != reflect.TypeFor[L]() {
+		panic(fmt.Sprintf("config.At: '%s' is %s, not %s", path, t, reflect.TypeFor[L]()))
+	}
+	return func(v T) error {
+		leaf, err := getPath(reflect.ValueOf(&v).Elem(), segs)
+		if err != nil {
+			return nil // nothing to check
+		}
+		for _, check := range checks {
+			if err := check(leaf.Interface().(L)); err != nil {
+				var le *leafError
+				if errors.As(err, &le) {
+					return &leafError{path: path + le.path, err: le.err}
+				}
+				return &leafError{path: path, err: err}
+			}
+		}
+		return nil
+	}
+}
+
+// leafError is the error of a check on part of a value, e.g. ".origins[1]: must be an absolute URL".
+type leafError struct {
+	path string
+	err  error
+}
+
+func (e *leafError) Error() string { return strings.TrimPrefix(e.path, ".") + ": " + e.err.Error() }
+
+func (e *leafError) Unwrap() error { return e.err }
+
+// Validate runs the per-key validators on val.
+func (v *value) Validate(val any) error {
+	if val == nil || reflect.TypeOf(val) != v.t {
+		return fmt.Errorf("expected %s, got %T", v.t, val)
+	}
+	for _, check := range v.checks {
+		if err := check(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAll runs every per-key validator and cross-key rule of the current schema over values.
+// All failures are returned joined together, not just the first.
+func (cfg *Config) validateAll(values map[string]any) error {
+	var errs []error
+	for _, key := range cfg.Keys() {
+		if err := cfg.Schemas[cfg.Version][key].Validate(values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for key '%s': %w", key, err))
+		}
+	}
+	if err := cfg.checkRules(values); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// checkRules runs the cross-key rules of the current schema over values.
+func (cfg *Config) checkRules(values map[string]any) error {
+	var errs []error
+	for _, rule := range cfg.Rules[cfg.Version] {
+		if err := rule(values); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the stored configuration, and the effective one if any env overrides are set,
+// against the validators and rules of the current schema.
+func (cfg *Config) Validate() error {
+	var stored, effective map[string]any
+	if err := cfg.DB.View(func(txn *lmdb.Txn) (err error) {
+		if stored, err = cfg.storedValues(txn); err != nil {
+			return err
+		}
+		effective, err = cfg.effectiveValues(txn)
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := cfg.validateAll(stored); err != nil {
+		return err
+	}
+	if err := cfg.validateAll(effective); err != nil {
+		return fmt.Errorf("environment overrides are invalid: %w", err)
+	}
+	return nil
+}
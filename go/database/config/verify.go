@@ -0,0 +1,261 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// ChangeKind is the kind of a [KeyChange].
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeRenamed ChangeKind = "renamed" // new key declaring the old one as alias, or backed by the same Go field
+	ChangeType    ChangeKind = "type"
+	ChangeDefault ChangeKind = "default" // computed defaults are compared by their doc
+)
+
+// KeyChange is a change of a single key between two schema versions.
+type KeyChange struct {
+	Kind             ChangeKind
+	Key              string // key in the newer schema, the removed key for ChangeRemoved
+	OldKey           string // set for ChangeRenamed
+	OldType, NewType string // Go type names, e.g. "int" or "config.Example"
+	OldDefault       any    // set for ChangeDefault
+	NewDefault       any
+	OldDefaultDoc    string // how a computed default is derived, set instead of OldDefault / NewDefault
+	NewDefaultDoc    string
+}
+
+func (c KeyChange) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("added '%s' (%s)", c.Key, c.NewType)
+	case ChangeRemoved:
+		return fmt.Sprintf("removed '%s' (%s)", c.Key, c.OldType)
+	case ChangeRenamed:
+		s := fmt.Sprintf("renamed '%s' -> '%s'", c.OldKey, c.Key)
+		if c.OldType != c.NewType {
+			s += fmt.Sprintf(", type %s -> %s", c.OldType, c.NewType)
+		}
+		return s
+	case ChangeType:
+		return fmt.Sprintf("type of '%s' %s -> %s", c.Key, c.OldType, c.NewType)
+	default:
+		return fmt.Sprintf("default of '%s' %s -> %s", c.Key, defaultString(c.OldDefault, c.OldDefaultDoc), defaultString(c.NewDefault, c.NewDefaultDoc))
+	}
+}
+
+// defaultString formats a default for a [KeyChange], strings quoted so an empty one shows.
+func defaultString(val any, doc string) string {
+	if doc != "" {
+		return "computed (" + doc + ")"
+	}
+	if s, ok := val.(string); ok {
+		return strconv.Quote(s)
+	}
+	return Format(val)
+}
+
+// SchemaDiff lists the key changes between two schema versions, see [Config.SchemaDiff].
+type SchemaDiff struct {
+	From, To string
+	Changes  []KeyChange // sorted by key
+}
+
+func (d SchemaDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s -> %s:", d.From, d.To)
+	if len(d.Changes) == 0 {
+		b.WriteString(" no key changes")
+	}
+	for _, c := range d.Changes {
+		fmt.Fprintf(&b, "\n  %s", c)
+	}
+	return b.String()
+}
+
+// Verify checks SchemaRecord, Version, Migrations and RuleRecord against each other, see [Config.Verify].
+// Use it from a test, e.g. with `configtest.Verify(t)`, so mistakes are caught before a release.
+func Verify() error {
+	cfg := &Config{Version: Version, Schemas: SchemaRecord, Migrations: Migrations, Rules: RuleRecord}
+	return cfg.Verify()
+}
+
+// Verify checks that:
+//   - every schema version is valid semver and Version is the newest one
+//   - the "version" default of every schema matches its version
+//   - every older version can reach Version through registered migrations
+//   - every migration and rule set refers to known versions
+//   - the defaults of every version pass its validators and rules
+//   - aliases have a valid Until version and don't clash with keys or each other
+//
+// All problems are returned joined together. It doesn't need a database.
+func (cfg *Config) Verify() error {
+	var errs []error
+	ctx := cfg.context()
+	versions := cfg.schemaVersions()
+	for _, v := range versions {
+		if !semver.IsValid(v) {
+			errs = append(errs, fmt.Errorf("schema version '%s' is not valid semver", v))
+		}
+	}
+	if _, ok := cfg.Schemas[cfg.Version]; !ok {
+		errs = append(errs, fmt.Errorf("current version '%s' has no schema", cfg.Version))
+	} else if newest := versions[len(versions)-1]; newest != cfg.Version {
+		errs = append(errs, fmt.Errorf("current version is '%s' but the newest schema is '%s'", cfg.Version, newest))
+	}
+
+	for _, v := range versions {
+		s := cfg.Schemas[v]
+		if def, ok := s["version"]; !ok {
+			errs = append(errs, fmt.Errorf("schema '%s' has no 'version' key", v))
+		} else if d, _ := def.DefaultValue(ctx).(string); d != v {
+			errs = append(errs, fmt.Errorf("schema '%s' has 'version' default '%v'", v, def.DefaultValue(ctx)))
+		}
+		values := make(map[string]any, len(s))
+		for key, value := range s {
+			values[key] = value.DefaultValue(ctx)
+			if err := value.Validate(values[key]); err != nil {
+				errs = append(errs, fmt.Errorf("schema '%s': default of key '%s' is invalid: %w", v, key, err))
+			}
+		}
+		aliased := map[string]string{} // alias -> key
+		for key, value := range s {
+			for _, a := range value.Aliases() {
+				switch other, dup := aliased[a.Name]; {
+				case !semver.IsValid(a.Until):
+					errs = append(errs, fmt.Errorf("schema '%s': alias '%s' of key '%s' has invalid version '%s'", v, a.Name, key, a.Until))
+				case dup:
+					errs = append(errs, fmt.Errorf("schema '%s': alias '%s' is declared by keys '%s' and '%s'", v, a.Name, other, key))
+				case s[a.Name] != nil:
+					errs = append(errs, fmt.Errorf("schema '%s': alias '%s' of key '%s' is a key itself", v, a.Name, key))
+				}
+				aliased[a.Name] = key
+			}
+		}
+		for _, rule := range cfg.Rules[v] {
+			if err := rule(values); err != nil {
+				errs = append(errs, fmt.Errorf("schema '%s': defaults break a rule: %w", v, err))
+			}
+		}
+		if semver.IsValid(v) && semver.Compare(v, cfg.Version) < 0 {
+			if _, err := cfg.migrationPath(v, cfg.Version); err != nil {
+				errs = append(errs, fmt.Errorf("schema '%s' can't be migrated: %w", v, err))
+			}
+		}
+	}
+
+	for step := range cfg.Migrations {
+		from, to, _ := strings.Cut(step, "->")
+		for _, v := range []string{from, to} {
+			if _, ok := cfg.Schemas[v]; !ok {
+				errs = append(errs, fmt.Errorf("migration '%s' refers to unknown schema '%s'", step, v))
+			}
+		}
+	}
+	for v := range cfg.Rules {
+		if _, ok := cfg.Schemas[v]; !ok {
+			errs = append(errs, fmt.Errorf("rules registered for unknown schema '%s'", v))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SchemaDiffs returns the key changes between every pair of adjacent schema versions, oldest first.
+func (cfg *Config) SchemaDiffs() []SchemaDiff {
+	versions := cfg.schemaVersions()
+	var diffs []SchemaDiff
+	for i := 1; i < len(versions); i++ {
+		d, _ := cfg.SchemaDiff(versions[i-1], versions[i]) // both exist
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// SchemaDiff returns the key changes between two schema versions, they don't have to be adjacent.
+// Use [Config.ScaffoldMigration] to turn it into a migration function.
+func (cfg *Config) SchemaDiff(from, to string) (SchemaDiff, error) {
+	d := SchemaDiff{From: from, To: to}
+	old, ok := cfg.Schemas[from]
+	if !ok {
+		return d, fmt.Errorf("unknown schema version '%s'", from)
+	}
+	new, ok := cfg.Schemas[to]
+	if !ok {
+		return d, fmt.Errorf("unknown schema version '%s'", to)
+	}
+	ctx := context.Background()
+	added := map[string]bool{}
+	for key, v := range new {
+		o, ok := old[key]
+		switch {
+		case !ok:
+			added[key] = true
+		case o.TypeName() != v.TypeName():
+			d.Changes = append(d.Changes, KeyChange{Kind: ChangeType, Key: key, OldType: o.TypeName(), NewType: v.TypeName()})
+		case key == "version":
+		case o.DefaultDoc() != v.DefaultDoc() || (o.DefaultDoc() == "" && !reflect.DeepEqual(o.DefaultValue(ctx), v.DefaultValue(ctx))):
+			c := KeyChange{Kind: ChangeDefault, Key: key, OldType: o.TypeName(), NewType: v.TypeName(),
+				OldDefaultDoc: o.DefaultDoc(), NewDefaultDoc: v.DefaultDoc()}
+			if c.OldDefaultDoc == "" {
+				c.OldDefault = o.DefaultValue(ctx)
+			}
+			if c.NewDefaultDoc == "" {
+				c.NewDefault = v.DefaultValue(ctx)
+			}
+			d.Changes = append(d.Changes, c)
+		}
+	}
+	// a removed and an added key that declares it as alias, or is backed by the same Go field, is a rename
+	for oldKey, o := range old {
+		if _, ok := new[oldKey]; ok {
+			continue
+		}
+		newKey := ""
+		for key := range added {
+			if slices.ContainsFunc(new[key].Aliases(), func(a Alias) bool { return a.Name == oldKey }) {
+				newKey = key
+				break
+			}
+		}
+		if newKey == "" && o.field != "" {
+			for key := range added {
+				if new[key].field == o.field {
+					newKey = key
+					break
+				}
+			}
+		}
+		if newKey == "" {
+			d.Changes = append(d.Changes, KeyChange{Kind: ChangeRemoved, Key: oldKey, OldType: o.TypeName()})
+			continue
+		}
+		delete(added, newKey)
+		d.Changes = append(d.Changes, KeyChange{Kind: ChangeRenamed, Key: newKey, OldKey: oldKey, OldType: o.TypeName(), NewType: new[newKey].TypeName()})
+	}
+	for key := range added {
+		d.Changes = append(d.Changes, KeyChange{Kind: ChangeAdded, Key: key, NewType: new[key].TypeName()})
+	}
+	sort.Slice(d.Changes, func(i, j int) bool { return d.Changes[i].Key < d.Changes[j].Key })
+	return d, nil
+}
+
+// schemaVersions returns the versions in Schemas, oldest first.
+func (cfg *Config) schemaVersions() []string {
+	versions := make([]string, 0, len(cfg.Schemas))
+	for v := range cfg.Schemas {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return semver.Compare(versions[i], versions[j]) < 0 })
+	return versions
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaDiff(t *testing.T) {
+	type v1_0_0 struct {
+		Version string `cfg:"version,internal" default:"v1.0.0"`
+		Port    int    `cfg:"port" default:"8080"`
+		Host    string `cfg:"host"`
+		Timeout int    `cfg:"timeout" default:"30"`
+		Debug   bool   `cfg:"debug"`
+		Mode    string `cfg:"mode" default:"fast"`
+		Dir     string `cfg:"dir"`
+	}
+	type v1_1_0 struct {
+		Version  string  `cfg:"version,internal" default:"v1.1.0"`
+		Port     int     `cfg:"port" default:"9090"`
+		Hostname string  `cfg:"hostname" alias:"host@v1.1.0"`
+		Timeout  float64 `cfg:"timeout" default:"30"`
+		Debug    bool    `cfg:"verbose"` // same field, renamed key
+		Retries  int     `cfg:"retries"`
+		Dir      string  `cfg:"dir"`
+	}
+	cfg := &Config{Schemas: map[string]schema{"v1.0.0": SchemaOf[v1_0_0](), "v1.1.0": SchemaOf[v1_1_0](
+		DefaultFunc("dir", func(context.Context) string { return "/data" }, "<data path>"),
+	)}}
+
+	d, err := cfg.SchemaDiff("v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []KeyChange{
+		{Kind: ChangeDefault, Key: "dir", OldType: "string", NewType: "string", OldDefault: "", NewDefaultDoc: "<data path>"},
+		{Kind: ChangeRenamed, Key: "hostname", OldKey: "host", OldType: "string", NewType: "string"},
+		{Kind: ChangeRemoved, Key: "mode", OldType: "string"},
+		{Kind: ChangeDefault, Key: "port", OldType: "int", NewType: "int", OldDefault: 8080, NewDefault: 9090},
+		{Kind: ChangeAdded, Key: "retries", NewType: "int"},
+		{Kind: ChangeType, Key: "timeout", OldType: "int", NewType: "float64"},
+		{Kind: ChangeRenamed, Key: "verbose", OldKey: "debug", OldType: "bool", NewType: "bool"},
+	}
+	if !reflect.DeepEqual(d.Changes, want) {
+		t.Fatalf("got changes\n%v\nwant\n%v", d.Changes, want)
+	}
+	if diffs := cfg.SchemaDiffs(); len(diffs) != 1 || !reflect.DeepEqual(diffs[0], d) {
+		t.Fatalf("SchemaDiffs = %v, want [%v]", diffs, d)
+	}
+	if _, err := cfg.SchemaDiff("v1.0.0", "v2.0.0"); err == nil {
+		t.Fatal("diff to an unknown version succeeded")
+	}
+
+	src, err := cfg.ScaffoldMigration("v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"func migrateV1_0_0toV1_1_0(", `txn.Get(dbi, []byte("host"))`, `txn.Del(dbi, []byte("debug"), nil)`, "var timeout int", `txn.Del(dbi, []byte("dir"), nil)`} {
+		if !strings.Contains(src, s) {
+			t.Errorf("scaffold lacks %q:\n%s", s, src)
+		}
+	}
+}
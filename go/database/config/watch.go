@@ -0,0 +1,218 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Data-Corruption/lmdb-go/lmdb"
+	"github.com/Data-Corruption/stdx/xlog"
+)
+
+// WatchInterval is how often the watcher polls the database for changes, including ones made by other processes.
+const WatchInterval = time.Second
+
+// Change describes a key whose effective value changed.
+type Change struct {
+	Key string
+	Old any
+	New any
+}
+
+// watcher polls the LMDB txn ID, which every committed write bumps, and diffs the
+// effective config when it moves. A single poll loop per Config serves all subscribers,
+// it runs while there are any (or until the Config's ctx is done).
+type watcher struct {
+	mu   sync.Mutex
+	subs []subscriber
+	next int                // ID of the next subscriber
+	stop context.CancelFunc // stops the poll loop, nil if it isn't running
+}
+
+type subscriber struct {
+	id   int
+	keys map[string]struct{} // nil means all keys
+	fn   func(Change)
+}
+
+// OnChange calls fn whenever the effective value of key changes, e.g. after `goweb config set` from another process.
+// Callbacks run on the watcher goroutine, one at a time. fn is removed when ctx is done.
+func OnChange[T any](ctx context.Context, key string, fn func(old, new T)) error {
+	cfg := FromContext(ctx)
+	if cfg == nil {
+		return fmt.Errorf("config not found in context")
+	}
+	key, err := cfg.canonical(key)
+	if err != nil {
+		return err
+	}
+	if _, err := typed[T](cfg, key); err != nil {
+		return err
+	}
+	cfg.subscribe(ctx, []string{key}, func(c Change) {
+		old, _ := c.Old.(T)
+		n, _ := c.New.(T)
+		fn(old, n)
+	})
+	return nil
+}
+
+// Watch returns a channel that receives a Change for every change of the given keys, or of all keys if none are given.
+// The channel is closed when ctx is done.
+func Watch(ctx context.Context, keys ...string) (<-chan Change, error) {
+	cfg := FromContext(ctx)
+	if cfg == nil {
+		return nil, fmt.Errorf("config not found in context")
+	}
+	canonical := make([]string, len(keys))
+	for i, key := range keys {
+		key, err := cfg.canonical(key)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := cfg.lookup(key); err != nil {
+			return nil, err
+		}
+		canonical[i] = key
+	}
+	keys = canonical
+	ch := make(chan Change, 16)
+	var mu sync.Mutex
+	closed := false
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		defer mu.Unlock()
+		closed = true
+		close(ch)
+	}()
+	cfg.subscribe(ctx, keys, func(c Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- c:
+		default:
+			xlog.Warnf(ctx, "config watch channel full, dropped change of key '%s'", c.Key)
+		}
+	})
+	return ch, nil
+}
+
+// subscribe adds fn until ctx is done, starting the poll loop for the first subscriber
+// and stopping it when the last one leaves.
+func (cfg *Config) subscribe(ctx context.Context, keys []string, fn func(Change)) {
+	sub := subscriber{fn: fn}
+	if len(keys) > 0 {
+		sub.keys = make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			sub.keys[key] = struct{}{}
+		}
+	}
+	w := &cfg.watch
+	w.mu.Lock()
+	sub.id = w.next
+	w.next++
+	w.subs = append(w.subs, sub)
+	if w.stop == nil {
+		parent := cfg.ctx // Init's ctx, not the subscriber's, other subscribers may outlive it
+		if parent == nil {
+			parent = context.Background()
+		}
+		loopCtx, stop := context.WithCancel(parent)
+		w.stop = stop
+		go cfg.watchLoop(loopCtx)
+	}
+	w.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.subs = slices.DeleteFunc(w.subs, func(s subscriber) bool { return s.id == sub.id })
+		if len(w.subs) == 0 && w.stop != nil {
+			w.stop()
+			w.stop = nil
+		}
+	}()
+}
+
+func (cfg *Config) watchLoop(ctx context.Context) {
+	var lastID uintptr
+	var last map[string]any
+	poll := func() (uintptr, map[string]any, error) {
+		var id uintptr
+		var values map[string]any
+		err := cfg.DB.View(func(txn *lmdb.Txn) (err error) {
+			id = txn.ID()
+			if id == lastID {
+				return nil
+			}
+			values, err = cfg.effectiveValues(txn)
+			return err
+		})
+		return id, values, err
+	}
+	var lastErr string // logged once until a poll succeeds again, e.g. for an invalid env override
+	check := func() {
+		id, values, err := poll()
+		if err != nil {
+			if err.Error() != lastErr {
+				xlog.Errorf(ctx, "config watcher failed to read config: %s", err)
+				lastErr = err.Error()
+			}
+			return
+		}
+		if lastErr != "" {
+			xlog.Infof(ctx, "config watcher reads the config again")
+			lastErr = ""
+		}
+		if id == lastID {
+			return
+		}
+		lastID = id
+		if last == nil { // first successful poll, nothing to diff against yet
+			last = values
+			return
+		}
+		for _, key := range cfg.Keys() {
+			if reflect.DeepEqual(last[key], values[key]) {
+				continue
+			}
+			xlog.Debugf(ctx, "config key '%s' changed", key)
+			cfg.notify(Change{Key: key, Old: last[key], New: values[key]})
+		}
+		last = values
+	}
+	check()
+
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+func (cfg *Config) notify(c Change) {
+	cfg.watch.mu.Lock()
+	subs := append([]subscriber(nil), cfg.watch.subs...)
+	cfg.watch.mu.Unlock()
+	for _, sub := range subs {
+		if sub.keys != nil {
+			if _, ok := sub.keys[c.Key]; !ok {
+				continue
+			}
+		}
+		sub.fn(c)
+	}
+}
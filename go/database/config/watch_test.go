@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatchOutlivesFirstSubscriber(t *testing.T) {
+	type v1_0_0 struct {
+		Version string `cfg:"version,internal" default:"v1.0.0"`
+		Port    int    `cfg:"port" default:"8080"`
+	}
+	cfg := testConfig(t, "v1.0.0", map[string]schema{"v1.0.0": SchemaOf[v1_0_0]()}, nil)
+	ctx := IntoContext(cfg.ctx, cfg)
+
+	first, cancelFirst := context.WithCancel(ctx)
+	if err := OnChange(first, "port", func(old, new int) {}); err != nil {
+		t.Fatal(err)
+	}
+	second, cancelSecond := context.WithCancel(ctx)
+	defer cancelSecond()
+	changed := make(chan int, 1)
+	if err := OnChange(second, "port", func(old, new int) { changed <- new }); err != nil {
+		t.Fatal(err)
+	}
+	cancelFirst()
+	waitFor(t, func() bool { n, _ := watchState(cfg); return n == 1 })
+
+	if err := Set(ctx, "port", 9000); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case port := <-changed:
+		if port != 9000 {
+			t.Fatalf("got port %d, want 9000", port)
+		}
+	case <-time.After(3 * WatchInterval):
+		t.Fatal("no change after the first subscriber's ctx was done")
+	}
+
+	cancelSecond()
+	waitFor(t, func() bool { n, running := watchState(cfg); return n == 0 && !running })
+}
+
+// watchState returns the number of subscribers and whether the poll loop runs.
+func watchState(cfg *Config) (int, bool) {
+	cfg.watch.mu.Lock()
+	defer cfg.watch.mu.Unlock()
+	return len(cfg.watch.subs), cfg.watch.stop != nil
+}
+
+// waitFor fails t if cond doesn't become true within a second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func TestWatchSeedsAfterFailedPoll(t *testing.T) {
+	type v1_0_0 struct {
+		Version string `cfg:"version,internal" default:"v1.0.0"`
+		Port    int    `cfg:"port" default:"8080"`
+	}
+	cfg := testConfig(t, "v1.0.0", map[string]schema{"v1.0.0": SchemaOf[v1_0_0]()}, nil)
+	ctx, cancel := context.WithCancel(IntoContext(cfg.ctx, cfg))
+	defer cancel()
+
+	t.Setenv(EnvName("port"), "not a number") // the initial poll fails
+	changes := make(chan [2]int, 4)
+	if err := OnChange(ctx, "port", func(old, new int) { changes <- [2]int{old, new} }); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(WatchInterval / 2)
+	os.Unsetenv(EnvName("port"))
+	time.Sleep(2 * WatchInterval) // a poll succeeds, seeding the values to diff against
+
+	if err := Set(ctx, "port", 9000); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-changes:
+		if c != [2]int{8080, 9000} {
+			t.Fatalf("got change %d -> %d, want 8080 -> 9000", c[0], c[1])
+		}
+	case <-time.After(3 * WatchInterval):
+		t.Fatal("no change")
+	}
+}
@@ -4,27 +4,49 @@ package database
 import (
 	"context"
 	"errors"
+	"fmt"
 	"goweb/go/database/datapath"
+	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
 
+	"github.com/Data-Corruption/lmdb-go/lmdb"
 	"github.com/Data-Corruption/lmdb-go/wrap"
+	"github.com/Data-Corruption/stdx/xlog"
 )
 
 /*
 Database Layout:
 
 Config - see config package for details.
+ConfigProfiles - named sparse overlays of config keys, keyed by "profile/key". See `config/profile.go`.
+ConfigHistory - append only log of config changes, keyed by big endian uint64 entry ID. See `config/history.go`.
+ConfigMeta - bookkeeping of the config package, i.e. the "generation" counter every config write bumps. See `config/cache.go`.
 
 Add other db info here.
 
+Every DBI is registered with RegisterDBI from an init func of the package owning it (the config ones in `config/config.go`).
+New opens the registered DBIs and creates the missing ones in place (logged at info), so a release can add a DBI without touching
+existing data. DBIs found on disk that aren't registered (e.g. left by a newer release before a rollback) are
+logged as a warning and left alone.
+
+App records are best stored through a Collection (see `collection.go`) bound to their own registered DBI.
+
 */
 
 const (
-	ConfigDBIName = "config"
-	// Add more DBI names as needed, e.g., UserDBIName, SessionDBIName, etc. Also update the slice below to include them.
-	// WARNING: If you add more DBIs you'll need to clean and reinitialize the database from scratch pretty sure.
+	ConfigDBIName         = "config"
+	ConfigHistoryDBIName  = "configHistory"
+	ConfigProfilesDBIName = "configProfiles"
+	ConfigMetaDBIName     = "configMeta"
+	// Add more DBI names as needed, e.g., UserDBIName, SessionDBIName, etc. and register them with RegisterDBI.
 )
 
+// DirName is the directory of the LMDB environment inside the data path.
+const DirName = "db"
+
 type ctxKey struct{}
 
 func IntoContext(ctx context.Context, db *wrap.DB) context.Context {
@@ -38,17 +60,134 @@ func FromContext(ctx context.Context) *wrap.DB {
 	return nil
 }
 
+// Copy writes a compacted copy (see [lmdb.CopyCompact]) of the environment in the data path to the directory dst,
+// which must exist. wrap doesn't expose its environment, so the copy goes through a separate read-only handle.
+// Compacting only takes a read txn (the plain copy waits for the write lock), so it works while the process holds
+// a write txn, but doesn't see that txn's writes.
+func Copy(dataPath, dst string) error {
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		return err
+	}
+	defer env.Close()
+	if err := env.SetMapSize(wrap.MapSize); err != nil {
+		return err
+	}
+	if err := env.Open(filepath.Join(dataPath, DirName), lmdb.Readonly, 0644); err != nil {
+		return err
+	}
+	return env.CopyFlag(dst, lmdb.CopyCompact)
+}
+
+// DBIOptions describes a DBI registered with [RegisterDBI].
+type DBIOptions struct {
+	Doc string // what it holds, e.g. "login sessions keyed by token", logged when the DBI is created
+}
+
+var registry = struct {
+	sync.Mutex
+	dbis   map[string]DBIOptions
+	opened bool
+}{dbis: map[string]DBIOptions{}}
+
+// RegisterDBI adds a DBI for [New] to open, creating it if the database doesn't have it yet. Call it from an init func.
+// Panics on an empty or duplicate name, or if the database was opened already.
+func RegisterDBI(name string, opts DBIOptions) {
+	registry.Lock()
+	defer registry.Unlock()
+	switch _, dup := registry.dbis[name]; {
+	case name == "":
+		panic("database: RegisterDBI with an empty name")
+	case dup:
+		panic(fmt.Sprintf("database: DBI '%s' registered twice", name))
+	case registry.opened:
+		panic(fmt.Sprintf("database: DBI '%s' registered after the database was opened", name))
+	}
+	registry.dbis[name] = opts
+}
+
+// Registered returns the names of the registered DBIs, sorted.
+func Registered() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	names := make([]string, 0, len(registry.dbis))
+	for name := range registry.dbis {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func New(ctx context.Context) (*wrap.DB, error) {
 	path := datapath.FromContext(ctx)
 	if path == "" {
 		return nil, errors.New("nexus data path not set before database initialization")
 	}
-	db, _, err := wrap.New(filepath.Join(path, "db"),
-		[]string{ConfigDBIName}, // If you add more DBIs, update this slice as well.
-	)
+	registry.Lock()
+	registry.opened = true
+	registry.Unlock()
+	names := Registered()
+
+	dir := filepath.Join(path, DirName)
+	existing, err := listDBIs(dir) // before wrap creates the missing ones
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DBIs: %w", err)
+	}
+	db, _, err := wrap.New(dir, names)
 	if err != nil {
-		db.Close()
 		return nil, err
 	}
+	for _, name := range names {
+		if !slices.Contains(existing, name) {
+			xlog.Infof(ctx, "created DBI '%s' (%s)", name, registry.dbis[name].Doc)
+		}
+	}
+	for _, name := range existing {
+		if !slices.Contains(names, name) {
+			xlog.Warnf(ctx, "DBI '%s' in the database isn't registered, leaving it alone. It may belong to a newer release", name)
+		}
+	}
 	return db, nil
 }
+
+// listDBIs returns the names of the DBIs in the environment in dir, none if it wasn't created yet.
+// It reads them through a read-only handle, the root DBI holds one entry per named DBI.
+func listDBIs(dir string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "data.mdb")); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		return nil, err
+	}
+	defer env.Close()
+	if err := env.SetMapSize(wrap.MapSize); err != nil {
+		return nil, err
+	}
+	if err := env.Open(dir, lmdb.Readonly, 0644); err != nil {
+		return nil, err
+	}
+	var names []string
+	err = env.View(func(txn *lmdb.Txn) error {
+		root, err := txn.OpenRoot(0)
+		if err != nil {
+			return err
+		}
+		cur, err := txn.OpenCursor(root)
+		if err != nil {
+			return err
+		}
+		defer cur.Close()
+		for {
+			k, _, err := cur.Get(nil, nil, lmdb.Next)
+			if lmdb.IsNotFound(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			names = append(names, string(k))
+		}
+	})
+	return names, err
+}
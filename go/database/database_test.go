@@ -0,0 +1,31 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"goweb/go/database/datapath"
+)
+
+const testDBIName = "test"
+
+func init() { RegisterDBI(testDBIName, DBIOptions{Doc: "records of the package tests"}) }
+
+func TestListDBIs(t *testing.T) {
+	dir := t.TempDir()
+	names, err := listDBIs(filepath.Join(dir, DirName))
+	if err != nil || names != nil {
+		t.Fatalf("got %v, %v before the database was created", names, err)
+	}
+	db, err := New(datapath.IntoContext(context.Background(), dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	names, err = listDBIs(filepath.Join(dir, DirName))
+	if err != nil || !slices.Equal(names, Registered()) {
+		t.Fatalf("got %v, %v, want %v", names, err, Registered())
+	}
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// KeyEncoder turns the keys of a [Collection] into LMDB keys and back. LMDB sorts keys bytewise, so encodings
+// should sort like the keys they encode for range scans to make sense.
+type KeyEncoder[K any] interface {
+	Encode(key K) ([]byte, error)
+	Decode(data []byte) (K, error)
+}
+
+// StringKeys stores string keys as is, prefix scans match string prefixes, e.g. "user/" for "user/42".
+var StringKeys KeyEncoder[string] = stringKeys{}
+
+// Uint64Keys stores uint64 keys as 8 bytes big endian so they sort numerically, like config history IDs.
+var Uint64Keys KeyEncoder[uint64] = uint64Keys{}
+
+// TupleKeys stores [Tuple] keys.
+var TupleKeys KeyEncoder[Tuple] = tupleKeys{}
+
+// Tuple is a composite key of strings and uint64s, e.g. Tuple{"user42", uint64(createdUnix)} for a user's sessions
+// in order. Tuples sort part by part, a prefix scan with a shorter Tuple matches whole leading parts, so Tuple{"user4"}
+// doesn't match Tuple{"user42", ...}.
+type Tuple []any
+
+type stringKeys struct{}
+
+func (stringKeys) Encode(key string) ([]byte, error) { return []byte(key), nil }
+
+func (stringKeys) Decode(data []byte) (string, error) { return string(data), nil }
+
+type uint64Keys struct{}
+
+func (uint64Keys) Encode(key uint64) ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, key), nil
+}
+
+func (uint64Keys) Decode(data []byte) (uint64, error) {
+	if len(data) != 8 {
+		return 0, fmt.Errorf("uint64 key has %d bytes, expected 8", len(data))
+	}
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// Each tuple part is a type byte followed by the value. Strings end with 0x00 0x01, zero bytes in them are escaped
+// as 0x00 0xff, so a string sorts before any longer one it's a prefix of and a shorter Tuple only prefixes whole parts.
+const (
+	tupleUint64 byte = 0x01
+	tupleString byte = 0x02
+)
+
+type tupleKeys struct{}
+
+func (tupleKeys) Encode(key Tuple) ([]byte, error) {
+	var b []byte
+	for i, part := range key {
+		switch v := part.(type) {
+		case uint64:
+			b = binary.BigEndian.AppendUint64(append(b, tupleUint64), v)
+		case string:
+			b = append(b, tupleString)
+			b = append(b, bytes.ReplaceAll([]byte(v), []byte{0x00}, []byte{0x00, 0xff})...)
+			b = append(b, 0x00, 0x01)
+		default:
+			return nil, fmt.Errorf("tuple part %d is a %T, only string and uint64 are supported", i, part)
+		}
+	}
+	return b, nil
+}
+
+func (tupleKeys) Decode(data []byte) (Tuple, error) {
+	var key Tuple
+	for len(data) > 0 {
+		kind := data[0]
+		data = data[1:]
+		switch kind {
+		case tupleUint64:
+			if len(data) < 8 {
+				return nil, errors.New("truncated uint64 in tuple key")
+			}
+			key = append(key, binary.BigEndian.Uint64(data))
+			data = data[8:]
+		case tupleString:
+			var s []byte
+			for {
+				i := bytes.IndexByte(data, 0x00)
+				if i < 0 {
+					return nil, errors.New("unterminated string in tuple key")
+				}
+				if i+1 >= len(data) || (data[i+1] != 0x01 && data[i+1] != 0xff) {
+					return nil, errors.New("invalid string in tuple key")
+				}
+				s = append(s, data[:i]...)
+				end := data[i+1] == 0x01
+				data = data[i+2:]
+				if end {
+					break
+				}
+				s = append(s, 0x00) // escaped zero byte
+			}
+			key = append(key, string(s))
+		default:
+			return nil, fmt.Errorf("unknown part type 0x%02x in tuple key", kind)
+		}
+	}
+	return key, nil
+}
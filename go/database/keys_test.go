@@ -0,0 +1,155 @@
+package database
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestStringKeys(t *testing.T) {
+	for _, key := range []string{"", "a", "user/42", "a\x00b"} {
+		data, err := StringKeys.Encode(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := StringKeys.Decode(data)
+		if err != nil || got != key {
+			t.Fatalf("round trip of %q: got %q, %v", key, got, err)
+		}
+	}
+}
+
+func TestUint64Keys(t *testing.T) {
+	ordered := []uint64{0, 1, 255, 256, 1 << 32, math.MaxUint64}
+	var prev []byte
+	for _, key := range ordered {
+		data, err := Uint64Keys.Encode(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Uint64Keys.Decode(data)
+		if err != nil || got != key {
+			t.Fatalf("round trip of %d: got %d, %v", key, got, err)
+		}
+		if prev != nil && bytes.Compare(prev, data) >= 0 {
+			t.Fatalf("%d doesn't sort after the previous key", key)
+		}
+		prev = data
+	}
+	if _, err := Uint64Keys.Decode([]byte{1, 2, 3}); err == nil {
+		t.Fatal("decoded a 3 byte key")
+	}
+}
+
+func TestTupleKeysRoundTrip(t *testing.T) {
+	tests := []Tuple{
+		nil,
+		{"bob"},
+		{""},
+		{uint64(0)},
+		{"bob", uint64(42)},
+		{uint64(7), "x", uint64(math.MaxUint64)},
+		{"a\x00b"},       // embedded zero byte
+		{"a\x00", "b"},   // ... right before the terminator
+		{"\x00\x01"},     // looks like a terminator
+		{"\x00\xff\x00"}, // looks like an escape
+		{"", "", ""},
+	}
+	for _, key := range tests {
+		data, err := TupleKeys.Encode(key)
+		if err != nil {
+			t.Fatalf("failed to encode %q: %s", key, err)
+		}
+		got, err := TupleKeys.Decode(data)
+		if err != nil {
+			t.Fatalf("failed to decode %q: %s", key, err)
+		}
+		if !reflect.DeepEqual(got, key) {
+			t.Fatalf("round trip of %q: got %q", key, got)
+		}
+	}
+}
+
+func TestTupleKeysOrder(t *testing.T) {
+	// each tuple must sort before the next one
+	ordered := []Tuple{
+		{uint64(1)},
+		{uint64(1), "a"},
+		{uint64(2)},
+		{uint64(256)},
+		{""},
+		{"", uint64(0)},
+		{"\x00"},
+		{"\x00\x00"},
+		{"\x01"},
+		{"bob"},
+		{"bob", uint64(1)},
+		{"bob", uint64(2)},
+		{"bob", "a"},
+		{"bob\x00"},
+		{"bob\x00x"},
+		{"bobby"},
+	}
+	var prev []byte
+	for i, key := range ordered {
+		data, err := TupleKeys.Encode(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && bytes.Compare(prev, data) >= 0 {
+			t.Fatalf("%q doesn't sort after %q", key, ordered[i-1])
+		}
+		prev = data
+	}
+}
+
+func TestTupleKeysPrefix(t *testing.T) {
+	// a tuple is a byte prefix of another exactly if it's a prefix part by part
+	tests := []struct {
+		prefix, key Tuple
+		want        bool
+	}{
+		{Tuple{"bob"}, Tuple{"bob", uint64(1)}, true},
+		{Tuple{"bob"}, Tuple{"bob"}, true},
+		{Tuple{"bob", uint64(1)}, Tuple{"bob", uint64(1), "x"}, true},
+		{Tuple{}, Tuple{"bob"}, true},
+		{Tuple{"bo"}, Tuple{"bob"}, false},
+		{Tuple{"bob"}, Tuple{"bobby"}, false},
+		{Tuple{"bob"}, Tuple{"bob\x00x"}, false},
+		{Tuple{"bob\x00"}, Tuple{"bob\x00x"}, false},
+		{Tuple{"a\x00"}, Tuple{"a", "b"}, false},
+		{Tuple{uint64(1)}, Tuple{uint64(256)}, false},
+		{Tuple{"1"}, Tuple{uint64(1)}, false},
+	}
+	for _, tt := range tests {
+		p, err := TupleKeys.Encode(tt.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, err := TupleKeys.Encode(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bytes.HasPrefix(k, p); got != tt.want {
+			t.Errorf("%q prefixes %q: got %t, want %t", tt.prefix, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestTupleKeysInvalid(t *testing.T) {
+	if _, err := TupleKeys.Encode(Tuple{1}); err == nil {
+		t.Fatal("encoded an int part")
+	}
+	for _, data := range [][]byte{
+		{tupleUint64, 0, 0, 0},        // truncated uint64
+		{tupleString, 'a'},            // unterminated
+		{tupleString, 'a', 0x00},      // truncated terminator
+		{tupleString, 'a', 0x00, 'b'}, // invalid escape
+		{0x03},                        // unknown part type
+	} {
+		if key, err := TupleKeys.Decode(data); err == nil {
+			t.Errorf("decoded %x as %q", data, key)
+		}
+	}
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -80,54 +82,28 @@ func run() (int, error) {
 	defer db.Close()
 	xlog.Debug(ctx, "Database initialized")
 
-	// init config
-	ctx, err = config.Init(ctx)
-	if err != nil {
+	// init config, `config migrate` migrates (or previews) by itself and help output shouldn't touch the db.
+	// `config doctor` has to start with values that can't be read, so the config isn't applied for it
+	migrate := !skipMigrate(os.Args[1:])
+	apply := migrate && !isConfigCommand(os.Args[1:], "doctor")
+	cfgCtx, err := config.Init(ctx, migrate)
+	switch {
+	case errors.Is(err, config.ErrNewerConfig) && isCommand(os.Args[1:], "update"):
+		// db is from a newer release (e.g. after an installer rollback), still let the user update to one that
+		// supports it. The app runs without a config, `update` copes with that
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		apply = false
+	case err != nil:
 		return 1, fmt.Errorf("failed to initialize config: %w", err)
+	default:
+		ctx = cfgCtx
+		xlog.Debug(ctx, "Config initialized")
 	}
-	xlog.Debug(ctx, "Config initialized")
-
-	// set log level
-	cfgLogLevel, err := config.Get[string](ctx, "logLevel")
-	if err != nil {
-		return 1, fmt.Errorf("failed to get log level from config: %w", err)
-	}
-	if err := log.SetLevel(cfgLogLevel); err != nil {
-		return 1, fmt.Errorf("failed to set log level: %w", err)
-	}
-
-	// Update check
-	updateNotify, err := config.Get[bool](ctx, "updateNotify")
-	if err != nil {
-		return 1, fmt.Errorf("failed to get updateNotify from config: %w", err)
-	}
-	if updateNotify {
-		// get last update check time from config
-		tStr, err := config.Get[string](ctx, "lastUpdateCheck")
-		if err != nil {
-			return 1, fmt.Errorf("failed to get lastUpdateCheck from config: %w", err)
-		}
-		t, err := time.Parse(time.RFC3339, tStr)
-		if err != nil {
-			return 1, fmt.Errorf("failed to parse lastUpdateCheck time: %w", err)
-		}
-
-		// once a day, very lightweight check
-		if time.Since(t) > 24*time.Hour {
-			xlog.Debug(ctx, "Checking for updates...")
-
-			// update check time in config
-			if err := config.Set(ctx, "lastUpdateCheck", time.Now().Format(time.RFC3339)); err != nil {
-				return 1, fmt.Errorf("failed to set lastUpdateCheck in config: %w", err)
-			}
 
-			updateAvailable, err := update.Check(ctx)
-			if err != nil {
-				return 1, fmt.Errorf("failed to check for updates: %w", err)
-			}
-			if updateAvailable {
-				fmt.Println("Update available! Run 'goweb update check' to see details.")
-			}
+	// apply config, skipped if it wasn't migrated since reading keys may fail
+	if apply {
+		if err := applyConfig(ctx, log); err != nil {
+			return 1, err
 		}
 	}
 
@@ -142,6 +118,10 @@ func run() (int, error) {
 				Value: DefaultLogLevel,
 				Usage: "override log level (debug|info|warn|error|none)",
 			},
+			&cli.StringFlag{
+				Name:  "profile",
+				Usage: "use the config profile `NAME` (or set " + config.ProfileEnv + ")",
+			},
 			&cli.BoolFlag{
 				Name:    "yes",
 				Aliases: []string{"y"},
@@ -151,10 +131,31 @@ func run() (int, error) {
 		Commands: []*cli.Command{
 			commands.Update,
 			commands.Service,
+			commands.Config,
 		},
 		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
 			// insert app name into context
 			ctx = context.WithValue(ctx, commands.AppNameKey{}, Name)
+			// switch config profile, the log level may differ in it
+			if name := cmd.String("profile"); name != "" {
+				cfg := config.FromContext(ctx)
+				if cfg == nil {
+					return ctx, fmt.Errorf("--profile needs the config, which couldn't be opened")
+				}
+				if err := cfg.UseProfile(name); err != nil {
+					return ctx, err
+				}
+				if !apply {
+					return ctx, nil
+				}
+				level, err := config.Get[string](ctx, "logLevel")
+				if err != nil {
+					return ctx, err
+				}
+				if err := log.SetLevel(level); err != nil {
+					return ctx, err
+				}
+			}
 			// handle log level override
 			logLevel := cmd.String("log")
 			if logLevel != DefaultLogLevel {
@@ -173,3 +174,90 @@ func run() (int, error) {
 	}
 	return 0, nil
 }
+
+// applyConfig applies the startup related config, i.e. log level and the daily update check.
+func applyConfig(ctx context.Context, log *xlog.Logger) error {
+	// set log level
+	cfgLogLevel, err := config.Get[string](ctx, "logLevel")
+	if err != nil {
+		return fmt.Errorf("failed to get log level from config: %w", err)
+	}
+	if err := log.SetLevel(cfgLogLevel); err != nil {
+		return fmt.Errorf("failed to set log level: %w", err)
+	}
+
+	// Update check
+	updateNotify, err := config.Get[bool](ctx, "updateNotify")
+	if err != nil {
+		return fmt.Errorf("failed to get updateNotify from config: %w", err)
+	}
+	if updateNotify {
+		// get last update check time from config
+		tStr, err := config.Get[string](ctx, "lastUpdateCheck")
+		if err != nil {
+			return fmt.Errorf("failed to get lastUpdateCheck from config: %w", err)
+		}
+		t, err := time.Parse(time.RFC3339, tStr)
+		if err != nil {
+			return fmt.Errorf("failed to parse lastUpdateCheck time: %w", err)
+		}
+
+		// once a day, very lightweight check
+		if time.Since(t) > 24*time.Hour {
+			xlog.Debug(ctx, "Checking for updates...")
+
+			// update check time in config
+			if err := config.Set(ctx, "lastUpdateCheck", time.Now().Format(time.RFC3339)); err != nil {
+				return fmt.Errorf("failed to set lastUpdateCheck in config: %w", err)
+			}
+
+			updateAvailable, err := update.Check(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to check for updates: %w", err)
+			}
+			if updateAvailable {
+				fmt.Println("Update available! Run 'goweb update check' to see details.")
+			}
+		}
+	}
+	return nil
+}
+
+// skipMigrate reports whether the command line shouldn't migrate the config on startup,
+// that's `config migrate`, `config scaffold` (run while the new schema has no migration yet) and help / version output.
+func skipMigrate(args []string) bool {
+	positional, help := parseArgs(args)
+	if help || (len(positional) > 0 && positional[0] == "help") {
+		return true
+	}
+	return isConfigCommand(args, "migrate") || isConfigCommand(args, "scaffold")
+}
+
+// isCommand reports whether the command line runs the top level command name, root flags may come first.
+func isCommand(args []string, name string) bool {
+	positional, _ := parseArgs(args)
+	return len(positional) > 0 && positional[0] == name
+}
+
+// isConfigCommand reports whether the command line runs `config <name>`.
+func isConfigCommand(args []string, name string) bool {
+	positional, _ := parseArgs(args)
+	return len(positional) > 1 && positional[0] == "config" && positional[1] == name
+}
+
+// parseArgs returns the positional args and whether help or version output was requested.
+func parseArgs(args []string) (positional []string, help bool) {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-h", "--help", "-v", "--version":
+			help = true
+		case "--log", "--profile": // root flags taking a value
+			i++
+		default:
+			if !strings.HasPrefix(arg, "-") {
+				positional = append(positional, arg)
+			}
+		}
+	}
+	return positional, help
+}
@@ -2,17 +2,29 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"goweb/go/database/config"
 	"goweb/go/database/datapath"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 
 	"github.com/Data-Corruption/stdx/xhttp"
 	"github.com/Data-Corruption/stdx/xlog"
 )
 
+// settings are the config keys the server is built from, changing any of them restarts it (see Run).
+type settings struct {
+	Port        int    `cfg:"port"`
+	UseTLS      bool   `cfg:"useTLS"`
+	TLSKeyPath  string `cfg:"tlsKeyPath"`
+	TLSCertPath string `cfg:"tlsCertPath"`
+}
+
+var _ = config.Register[settings]()
+
 type ctxKey struct{}
 
 func IntoContext(ctx context.Context, srv *xhttp.Server) context.Context {
@@ -28,30 +40,21 @@ func FromContext(ctx context.Context) *xhttp.Server {
 
 func New(ctx context.Context, handler http.Handler) (*xhttp.Server, error) {
 	// get http server related stuff from config
-	port, err := config.Get[int](ctx, "port")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get port from config: %w", err)
-	}
-	useTLS, err := config.Get[bool](ctx, "useTLS")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get useTLS from config: %w", err)
-	}
-	tlsKeyPath, err := config.Get[string](ctx, "tlsKeyPath")
+	cfg, err := config.Load[settings](ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tlsKeyPath from config: %w", err)
-	}
-	tlsCertPath, err := config.Get[string](ctx, "tlsCertPath")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get tlsCertPath from config: %w", err)
+		return nil, fmt.Errorf("failed to load server config: %w", err)
 	}
+	return newServer(ctx, handler, cfg)
+}
 
-	// create http server
+// newServer creates the http server from cfg.
+func newServer(ctx context.Context, handler http.Handler, cfg settings) (*xhttp.Server, error) {
 	var srv *xhttp.Server
-	srv, err = xhttp.NewServer(&xhttp.ServerConfig{
-		Addr:        fmt.Sprintf(":%d", port),
-		UseTLS:      useTLS,
-		TLSKeyPath:  tlsKeyPath,
-		TLSCertPath: tlsCertPath,
+	srv, err := xhttp.NewServer(&xhttp.ServerConfig{
+		Addr:        fmt.Sprintf(":%d", cfg.Port),
+		UseTLS:      cfg.UseTLS,
+		TLSKeyPath:  cfg.TLSKeyPath,
+		TLSCertPath: cfg.TLSCertPath,
 		Handler:     handler,
 		AfterListen: func() {
 			// write health file
@@ -73,3 +76,84 @@ func New(ctx context.Context, handler http.Handler) (*xhttp.Server, error) {
 	})
 	return srv, err
 }
+
+// Run creates the server and blocks until it shuts down. When one of the keys it's built from
+// changes (e.g. `goweb config set port 9000`), it's gracefully shut down and recreated with the new config.
+// If the new config doesn't work (e.g. the port is taken or the TLS files can't be loaded), the error is logged
+// and the server comes back with the last config it ran with, until the keys change again.
+func Run(ctx context.Context, handler http.Handler) error {
+	changes, err := config.Watch(ctx, config.TaggedKeys[settings]()...)
+	if err != nil {
+		return fmt.Errorf("failed to watch config: %w", err)
+	}
+	cur, err := config.Load[settings](ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load server config: %w", err)
+	}
+	good := cur        // last config the server ran with
+	first := true      // nothing to fall back to before the first server ran
+	restoring := false // cur is good after the changed config failed
+	for {
+		srv, err := newServer(ctx, handler, cur)
+		if err == nil {
+			err = serve(ctx, srv, changes)
+		}
+		switch {
+		case errors.Is(err, errRestart):
+			first, restoring, good = false, false, cur
+		case err != nil && (first || restoring):
+			return err
+		case err != nil:
+			xlog.Errorf(ctx, "server failed with the changed config, restoring the previous one: %s", err)
+			fmt.Fprintf(os.Stderr, "Error: server failed with the changed config, restored the previous one: %s\n", err)
+			cur, restoring = good, true
+			continue
+		default:
+			return nil
+		}
+		// several keys often change at once, the new server already sees all of them
+		for drained := false; !drained; {
+			select {
+			case <-changes:
+			default:
+				drained = true
+			}
+		}
+		if cur, err = config.Load[settings](ctx); err != nil {
+			xlog.Errorf(ctx, "failed to load changed server config, keeping the previous one: %s", err)
+			cur = good
+		}
+	}
+}
+
+// errRestart is returned by serve when srv was shut down for a config change.
+var errRestart = errors.New("server restarting")
+
+// serve runs srv until it shuts down, shutting it down on the first value from changes.
+func serve(ctx context.Context, srv *xhttp.Server, changes <-chan config.Change) error {
+	var restarting atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		select {
+		case c, ok := <-changes:
+			if !ok {
+				return
+			}
+			xlog.Infof(ctx, "config key '%s' changed, restarting server", c.Key)
+			restarting.Store(true)
+			if err := srv.Shutdown(nil); err != nil {
+				xlog.Errorf(ctx, "failed to shut down server for restart: %s", err)
+			}
+		case <-done:
+		}
+	}()
+	err := srv.Listen()
+	close(done)
+	if err != nil {
+		return err
+	}
+	if restarting.Load() && ctx.Err() == nil {
+		return errRestart
+	}
+	return nil
+}
@@ -101,9 +101,11 @@ func Update(ctx context.Context, detach bool) error {
 	}
 	fmt.Println("New version available:", latest)
 
-	// update config
-	if err := config.Set(ctx, "updateAvailable", false); err != nil {
-		return fmt.Errorf("failed to set updateAvailable in config: %w", err)
+	// update config, unless it couldn't be opened because it's from a newer version
+	if config.FromContext(ctx) != nil {
+		if err := config.Set(ctx, "updateAvailable", false); err != nil {
+			return fmt.Errorf("failed to set updateAvailable in config: %w", err)
+		}
 	}
 
 	// run the install command